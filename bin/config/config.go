@@ -0,0 +1,101 @@
+package configCommand
+
+import (
+  "flag"
+  "fmt"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/logger"
+)
+
+/**
+* devlab config get <key> | set <key> <value> [--global] | list [--show-origin]
+*/
+func Call(commandArgs []string) (err error) {
+  if len(commandArgs) == 0 {
+    return usage()
+  }
+
+  flags := flag.NewFlagSet("config " + commandArgs[0], flag.ContinueOnError)
+  showOrigin := flags.Bool("show-origin", false, "show the layer and file or variable every value came from")
+  isGlobal := flags.Bool("global", false, "set value in global config instead of project .config")
+  params, err := args.Parse(flags, commandArgs[1:])
+  if err != nil { return }
+
+  switch commandArgs[0] {
+  case "get":
+    if len(params) != 1 { return usage() }
+    return Get(params[0], *showOrigin)
+  case "set":
+    if len(params) != 2 { return usage() }
+    err = config.Set(params[0], params[1], *isGlobal)
+    if errors.CheckAndReturnIfError(err) { return }
+  case "list":
+    return List(*showOrigin)
+  default:
+    return usage()
+  }
+
+  return
+}
+
+/**
+* Prints config value
+*/
+func Get(key string, showOrigin bool) (err error) {
+  values, err := config.LoadWithOrigins()
+  if _, isNotFound := err.(*files.MainConfigNotFoundError); isNotFound {
+    logger.Warn("%s\n", err)
+  } else if errors.CheckAndReturnIfError(err) { return }
+
+  value, ok := values[key]
+  if !ok {
+    err = fmt.Errorf("unknown config key '%s'", key)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  if showOrigin {
+    fmt.Printf("%s\t%s\n", value.Value, origin(value))
+  } else {
+    fmt.Println(value.Value)
+  }
+
+  return
+}
+
+/**
+* Prints all config values
+*/
+func List(showOrigin bool) (err error) {
+  values, err := config.LoadWithOrigins()
+  if _, isNotFound := err.(*files.MainConfigNotFoundError); isNotFound {
+    logger.Warn("%s\n", err)
+  } else if errors.CheckAndReturnIfError(err) { return }
+
+  for _, key := range config.Keys(values) {
+    if showOrigin {
+      fmt.Printf("%-28s %-32s %s\n", key + ":", values[key].Value, origin(values[key]))
+    } else {
+      fmt.Printf("%s: %s\n", key, values[key].Value)
+    }
+  }
+
+  return
+}
+
+func origin(value config.Value) string {
+  if value.Source == "" {
+    return value.Origin
+  }
+  return value.Origin + " (" + value.Source + ")"
+}
+
+func usage() error {
+  logger.Text("Usage: devlab config get <key> [--show-origin]")
+  logger.Text("       devlab config set <key> <value> [--global]")
+  logger.Text("       devlab config list [--show-origin]")
+  return fmt.Errorf("wrong config command arguments")
+}
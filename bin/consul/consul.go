@@ -0,0 +1,106 @@
+package consulCommand
+
+import (
+  "flag"
+  "fmt"
+  "strings"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/consul"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/logger"
+)
+
+const USAGE = "Usage: devlab consul diff|apply|export <context> [--prefix <prefix>] [--output <file>] [--dry-run]"
+
+/**
+* devlab consul diff|apply|export <context>: shows differences between keys declared for context (consul-kv section
+* and kv folder set in context section of settings.yml) and keys of consul, with apply puts declared keys into consul,
+* with export prints keys of consul (of prefix) as yaml of kv folder
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("consul", flag.ExitOnError)
+  prefix := flags.String("prefix", "", "prefix of exported keys")
+  output := flags.String("output", "", "file of exported keys (stdout by default)")
+  dryRun := flags.Bool("dry-run", false, "print commands instead of running them")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 2 {
+    logger.Text(USAGE)
+    return
+  }
+  command := params[0]
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  target, err := deploy.LoadTarget(config, params[1])
+  if errors.CheckAndReturnIfError(err) { return }
+  target.DryRun = *dryRun
+
+  backend, err := deploy.New(target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  switch command {
+  case "diff", "apply":
+    changes, err := consul.Changes(backend, target)
+    if errors.CheckAndReturnIfError(err) { return err }
+    Print(changes)
+
+    if command == "apply" {
+      err = consul.Apply(backend, changes)
+      if errors.CheckAndReturnIfError(err) { return err }
+      logger.Info("Keys are loaded into consul\n")
+    }
+
+  case "export":
+    actual, err := consul.Keys(backend)
+    if errors.CheckAndReturnIfError(err) { return err }
+
+    data, err := consul.Export(actual, *prefix)
+    if errors.CheckAndReturnIfError(err) { return err }
+
+    if *output == "" {
+      fmt.Print(data)
+      return nil
+    }
+    err = files.WriteFileAtomic(*output, data)
+    if errors.CheckAndReturnIfError(err) { return err }
+    logger.Info("Keys are exported to %s\n", *output)
+
+  default:
+    logger.Text(USAGE)
+  }
+
+  return
+}
+
+/**
+* Prints changes of keys (secrets are redacted)
+*/
+func Print(changes []*consul.Change) {
+  if len(changes) == 0 {
+    logger.Text("There are no keys")
+    return
+  }
+
+  width := 0
+  for _, change := range changes {
+    if len(change.Key) > width {
+      width = len(change.Key)
+    }
+  }
+
+  for _, change := range changes {
+    details := ""
+    switch change.Action {
+    case consul.ACTION_CREATE:
+      details = fmt.Sprintf("'%s'", change.Value)
+    case consul.ACTION_UPDATE:
+      details = fmt.Sprintf("'%s' -> '%s'", change.Current, change.Value)
+    }
+    logger.Text(logger.Redact(strings.TrimRight(fmt.Sprintf("%-*s  %-10s  %s", width, change.Key, change.Action, details), " ")))
+  }
+}
@@ -1,30 +1,35 @@
 package Context
 
 import (
+  "devlab/lib/config"
   "devlab/lib/logger"
   "devlab/lib/files"
   "devlab/lib/errors"
+  "devlab/lib/secrets"
   "devlab/lib/services"
+  "fmt"
+  "path/filepath"
+  "sort"
   "strings"
 )
 
 
 func Set(contextName string) (err error) {
-  config, err := files.ReadMainConfig()
+  config, err := config.Load()
   if errors.CheckAndReturnIfError(err) { return }
 
   // Check context dir and create it if need  
-  contextDir := "./" + config["contexts-path"] + "/" + contextName 
-  isContextDirExists, _ :=  files.IsExists("./" + contextDir)
+  contextDir := filepath.Join(config["contexts-path"], contextName)
+  isContextDirExists, _ :=  files.IsExists(contextDir)
   if !isContextDirExists {
-    files.CreateDir("./" + contextDir)
+    files.CreateDir(contextDir)
   }
   
   // Check context settings file and create it if need  
   contextSettings := contextDir + "/settings.yml"
   isContextSettingsExists, _ :=  files.IsExists(contextSettings)
   if !isContextSettingsExists {
-    files.Copy("./" + config["data-path"] + "/default-context.yml", contextSettings) 
+    files.Copy(filepath.Join(config["data-path"], "default-context.yml"), contextSettings) 
   }
 
   // Read context settings
@@ -32,11 +37,11 @@ func Set(contextName string) (err error) {
   if errors.CheckAndReturnIfError(err) { return }
 
   // Check context services dir and create it if need
-  contextServicesDir := config["contexts-path"] + "/" + contextName + "/services"
-  isContextServicesDirExists, err :=  files.IsExists("./" + contextServicesDir)
+  contextServicesDir := filepath.Join(contextDir, "services")
+  isContextServicesDirExists, err :=  files.IsExists(contextServicesDir)
   if errors.CheckAndReturnIfError(err) { return }
   if !isContextServicesDirExists {
-    files.CreateDir("./" + contextServicesDir)
+    files.CreateDir(contextServicesDir)
   }
 
   // Set task base branch
@@ -51,7 +56,7 @@ func Set(contextName string) (err error) {
 
     logger.Header(strings.ToUpper(serviceName))
 
-    isServiceDirExists, _ :=  files.IsExists("./" + contextServicesDir + "/serviceName") 
+    isServiceDirExists, _ :=  files.IsExists(contextServicesDir + "/serviceName") 
         
     if !isServiceDirExists {      
       services.Clone(contextServicesDir, serviceName, config["github-repository-path"], serviceParams["github-path"] )
@@ -70,5 +75,47 @@ func Set(contextName string) (err error) {
 func Create(contextName string) (err error) {
   return
 }
-  
 
+/**
+* Prints context settings with secrets redacted and state of every secret reference
+*/
+func Show(contextName string) (err error) {
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  contextSettings := filepath.Join(config["contexts-path"], contextName, "settings.yml")
+  settings, err := files.ReadTextFile(contextSettings)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  context, err := files.ReadContextConfig(contextSettings)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  var references []string
+  for section, sectionSettings := range context {
+    for group, groupSettings := range sectionSettings {
+      for key, value := range groupSettings {
+        if secrets.IsReference(value) {
+          references = append(references, section + "." + group + "." + key + "\t" + value)
+        }
+      }
+    }
+  }
+  sort.Strings(references)
+
+  logger.Header(contextSettings)
+  fmt.Print(logger.Redact(settings))
+
+  if len(references) == 0 { return }
+
+  logger.Header("SECRETS")
+  for _, reference := range references {
+    _, value, _ := strings.Cut(reference, "\t")
+    if _, err := secrets.Resolve(value); err != nil {
+      logger.Warn("%s\n", err)
+      continue
+    }
+    logger.Text(strings.Replace(reference, "\t", " = ", 1) + " (set)")
+  }
+
+  return
+}
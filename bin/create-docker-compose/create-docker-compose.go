@@ -1,13 +1,100 @@
 package createDockerCompose
 
 import (
+	"flag"
+	"path/filepath"
+	"sort"
+	"devlab/lib/args"
+	"devlab/lib/config"
+	"devlab/lib/env"
+	"devlab/lib/errors"
 	"devlab/lib/files"
+	"devlab/lib/library"
+	"devlab/lib/logger"
+	"devlab/lib/ports"
+	"devlab/lib/state"
   "devlab/lib/docker-compose-file-builder"
 )
 
-func Call(contextName string) {
-	config, _ := files.ReadMainConfig()
-	dockerComposeData := DockerComposeFileBuilder.CreateDockerComposeObjectExample()
-	contextDir := config["contexts-path"] + "/" + contextName
-	DockerComposeFileBuilder.Create("./" + contextDir + "/docker-compose.application.yml", dockerComposeData)
-}
\ No newline at end of file
+/**
+* devlab create-docker-compose <context> [--force]
+*/
+func Call(commandArgs []string) {
+	flags := flag.NewFlagSet("create-docker-compose", flag.ExitOnError)
+	force := flags.Bool("force", false, "overwrite docker compose files even if they were edited by hand")
+	params, err := args.Parse(flags, commandArgs)
+	errors.CheckAndExitIfError(err)
+	if len(params) != 1 {
+		logger.Text("Usage: devlab create-docker-compose <context> [--force]")
+		return
+	}
+
+	errors.CheckAndExitIfError(Create(params[0], *force))
+}
+
+/**
+* Creates system and application docker compose files of context with host ports allocated for the context,
+* context .env and per-service .env files
+*/
+func Create(contextName string, force bool) (err error) {
+	config, err := config.Load()
+	if err != nil { return }
+
+	contextDir := filepath.Join(config["contexts-path"], contextName)
+	context, err := files.ReadContextConfig(contextDir + "/settings.yml")
+	if err != nil { return }
+
+	applicationCompose, applicationWarnings, err := DockerComposeFileBuilder.ApplicationCompose(contextDir, contextName, context)
+	if err != nil { return }
+
+	componentsLibrary, err := library.ResolveForContext(config, contextDir, false)
+	if err != nil { return }
+
+	systemCompose, warnings, err := componentsLibrary.SystemCompose(contextDir, contextName, context)
+	if err != nil { return }
+	warnings = append(append(componentsLibrary.Warnings, warnings...), applicationWarnings...)
+
+	for _, dockerComposeData := range []*DockerComposeFileBuilder.DockerComposeFile{systemCompose, applicationCompose} {
+		translateWarnings, err := DockerComposeFileBuilder.Translate(dockerComposeData, config["docker-compose-version"])
+		if err != nil { return err }
+		warnings = append(warnings, translateWarnings...)
+	}
+
+	portsRange, err := ports.ParseRange(config["ports-range"])
+	if err != nil { return }
+
+	usedPorts, err := ports.UsedByOtherContexts(config["contexts-path"], contextName)
+	if err != nil { return }
+
+	contextState, err := state.Load(contextDir)
+	if err != nil { return }
+
+	portsWarnings, err := ports.Allocate([]*DockerComposeFileBuilder.DockerComposeFile{systemCompose, applicationCompose}, contextState, portsRange, usedPorts)
+	if err != nil { return }
+	warnings = append(warnings, portsWarnings...)
+
+	for _, warning := range warnings {
+		logger.Warn("%s\n", warning)
+	}
+
+	if err = DockerComposeFileBuilder.Create(contextDir + "/" + DockerComposeFileBuilder.SYSTEM_COMPOSE, systemCompose, force); err != nil { return }
+	if err = DockerComposeFileBuilder.Create(contextDir + "/" + DockerComposeFileBuilder.APPLICATION_COMPOSE, applicationCompose, force); err != nil { return }
+
+	if err = contextState.Save(contextDir); err != nil { return }
+
+	parameters, err := componentsLibrary.ParameterVariables(context)
+	if err != nil { return }
+
+	variables, err := env.ContextVariables(contextDir, contextName, config, context, contextState, parameters, []*DockerComposeFileBuilder.DockerComposeFile{systemCompose, applicationCompose})
+	if err != nil { return }
+
+	var applicationServices []string
+	for serviceName, serviceParams := range context["applicaton-services"] {
+		if serviceParams["enabled"] != "false" {
+			applicationServices = append(applicationServices, serviceName)
+		}
+	}
+	sort.Strings(applicationServices)
+
+	return env.Generate(contextDir, config["library-path"], variables, applicationServices, force)
+}
@@ -0,0 +1,129 @@
+package dbCommand
+
+import (
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/logger"
+  "devlab/lib/postgres"
+  "devlab/lib/util"
+)
+
+/* Folder of context with dumps of databases */
+const DUMPS_DIR = "dumps"
+
+/**
+* devlab db reset|seed|dump|restore <context> [db] [--file <path>] [--yes]: manages databases declared
+* in settings.yml of context (all of them if db is not set)
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("db", flag.ExitOnError)
+  file := flags.String("file", "", "dump file of dump and restore (<context>/dumps/<db>.sql by default)")
+  yes := flags.Bool("yes", false, "do not ask confirmation of reset and restore")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) < 2 || len(params) > 3 {
+    logger.Text("Usage: devlab db reset|seed|dump|restore <context> [db] [--file <path>] [--yes]")
+    return
+  }
+  command, contextName := params[0], params[1]
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  target, err := deploy.LoadTarget(config, contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  backend, err := deploy.New(target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  _, databases, err := postgres.Declared(target.Context, target.ContextDir)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  names := postgres.Names(databases)
+  if len(params) == 3 {
+    if _, ok := databases[params[2]]; !ok {
+      err = fmt.Errorf("database '%s' is not declared in %s section of settings.yml", params[2], postgres.DATABASES_SECTION)
+      errors.CheckAndReturnIfError(err)
+      return
+    }
+    names = []string{params[2]}
+  }
+  if len(names) == 0 {
+    logger.Text("There are no databases in " + postgres.DATABASES_SECTION + " section of settings.yml")
+    return
+  }
+  if *file != "" && len(names) > 1 {
+    err = fmt.Errorf("--file could be used with one database only")
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  client := postgres.NewClient(backend)
+  switch command {
+  case "reset", "restore":
+    if !*yes && !util.Confirm(fmt.Sprintf("Data of %s of context '%s' will be replaced, continue, y|N ? ", strings.Join(names, ", "), contextName)) {
+      return
+    }
+  case "seed", "dump":
+  default:
+    logger.Text("Usage: devlab db reset|seed|dump|restore <context> [db] [--file <path>] [--yes]")
+    return
+  }
+
+  for _, name := range names {
+    database := databases[name]
+    path := *file
+    if path == "" {
+      path = filepath.Join(target.ContextDir, DUMPS_DIR, name + ".sql")
+    }
+
+    switch command {
+    case "reset":
+      err = client.Reset(database)
+    case "seed":
+      err = client.Seed(database)
+    case "dump":
+      err = dump(client, database, path)
+    case "restore":
+      err = restore(client, database, path)
+    }
+    if errors.CheckAndReturnIfError(err) { return }
+    logger.Info("%s: %s is done\n", name, command)
+  }
+
+  return
+}
+
+func dump(client *postgres.Client, database *postgres.Database, path string) (err error) {
+  if err = files.CreateDir(filepath.Dir(path)); err != nil { return }
+
+  /* dump is written to temporary file, so failed dump doesn't replace previous one */
+  output, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path) + ".*")
+  if err != nil { return }
+  defer os.Remove(output.Name())
+
+  err = client.Dump(database, output)
+  output.Close()
+  if err != nil { return }
+
+  if err = os.Rename(output.Name(), path); err != nil { return }
+  logger.Text("Dump is written to " + path)
+
+  return
+}
+
+func restore(client *postgres.Client, database *postgres.Database, path string) (err error) {
+  input, err := os.Open(path)
+  if err != nil { return }
+  defer input.Close()
+
+  return client.Restore(database, input)
+}
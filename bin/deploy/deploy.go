@@ -0,0 +1,105 @@
+package deployCommand
+
+import (
+  "flag"
+  "time"
+  "devlab/bin/create-docker-compose"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/consul"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/kafka"
+  "devlab/lib/keycloak"
+  "devlab/lib/logger"
+  "devlab/lib/postgres"
+)
+
+/**
+* devlab up <context> [--force] [--dry-run] [--wait [--timeout <duration>]]: generates docker compose files
+* of context and runs services with deploy strategy set in config (docker-compose or kubernetes), provisions kafka
+* topics, postgres roles and databases, consul keys and keycloak realm declared in settings.yml, with --wait blocks until services with health checks are healthy
+*/
+func Up(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("up", flag.ExitOnError)
+  force := flags.Bool("force", false, "overwrite generated files even if they were edited by hand")
+  dryRun := flags.Bool("dry-run", false, "print commands instead of running them (kubernetes manifests are still rendered)")
+  wait := flags.Bool("wait", false, "wait until services with health checks are healthy")
+  timeout := flags.Duration("timeout", 5 * time.Minute, "how long to wait for services to become healthy")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 1 {
+    logger.Text("Usage: devlab up <context> [--force] [--dry-run] [--wait [--timeout <duration>]]")
+    return
+  }
+
+  err = createDockerCompose.Create(params[0], *force)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  backend, target, err := backend(params[0], *dryRun)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = backend.Up()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = kafka.Provision(backend, target.Context)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = postgres.Provision(backend, target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = consul.Provision(backend, target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = keycloak.Provision(backend, target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if *wait {
+    err = backend.Wait(*timeout)
+    errors.CheckAndReturnIfError(err)
+  }
+  return
+}
+
+/**
+* devlab down <context> [--dry-run]: stops and removes services of context
+*/
+func Down(commandArgs []string) (err error) {
+  return call("down", commandArgs, deploy.Backend.Down)
+}
+
+/**
+* devlab proxy <context> [--dry-run]: forwards host ports allocated for context to services (kubernetes)
+*/
+func Proxy(commandArgs []string) (err error) {
+  return call("proxy", commandArgs, deploy.Backend.Proxy)
+}
+
+func call(command string, commandArgs []string, action func(deploy.Backend) error) (err error) {
+  flags := flag.NewFlagSet(command, flag.ExitOnError)
+  dryRun := flags.Bool("dry-run", false, "print commands instead of running them")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 1 {
+    logger.Text("Usage: devlab " + command + " <context> [--dry-run]")
+    return
+  }
+
+  backend, _, err := backend(params[0], *dryRun)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  err = action(backend)
+  errors.CheckAndReturnIfError(err)
+  return
+}
+
+func backend(contextName string, dryRun bool) (backend deploy.Backend, target *deploy.Target, err error) {
+  config, err := config.Load()
+  if err != nil { return }
+
+  if target, err = deploy.LoadTarget(config, contextName); err != nil { return }
+  target.DryRun = dryRun
+
+  backend, err = deploy.New(target)
+  return
+}
@@ -0,0 +1,73 @@
+package envCommand
+
+import (
+  "fmt"
+  "path/filepath"
+  "devlab/lib/config"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/env"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/library"
+  "devlab/lib/logger"
+  "devlab/lib/state"
+)
+
+/**
+* devlab env show <context> [service]: prints context .env or rendered .env of service (secrets are redacted)
+*/
+func Call(commandArgs []string) (err error) {
+  if len(commandArgs) < 2 || len(commandArgs) > 3 || commandArgs[0] != "show" {
+    logger.Text("Usage: devlab env show <context> [service]")
+    return
+  }
+  contextName := commandArgs[1]
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  contextDir := filepath.Join(config["contexts-path"], contextName)
+  variables, err := contextVariables(contextDir, contextName, config)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if len(commandArgs) == 2 {
+    fmt.Print(logger.Redact(env.Format(variables)))
+    return
+  }
+
+  serviceName := commandArgs[2]
+  templatePath := env.FindTemplate(contextDir, config["library-path"], serviceName)
+  if templatePath == "" {
+    logger.Warn("Service '%s' has no .env template\n", serviceName)
+    return
+  }
+  logger.Info("# %s\n", templatePath)
+
+  content, err := env.RenderService(contextDir, config["library-path"], serviceName, variables)
+  if errors.CheckAndReturnIfError(err) { return }
+  fmt.Print(logger.Redact(content))
+
+  return
+}
+
+/**
+* Returns variables of context built from its settings, state and generated docker compose files
+*/
+func contextVariables(contextDir string, contextName string, config map[string]string) (variables map[string]string, err error) {
+  context, err := files.ReadContextConfig(contextDir + "/settings.yml")
+  if err != nil { return }
+
+  contextState, err := state.Load(contextDir)
+  if err != nil { return }
+
+  dockerComposeFiles, err := DockerComposeFileBuilder.ReadGenerated(contextDir, contextName)
+  if err != nil { return }
+
+  componentsLibrary, err := library.ForContext(config, contextDir)
+  if err != nil { return }
+
+  parameters, err := componentsLibrary.ParameterVariables(context)
+  if err != nil { return }
+
+  return env.ContextVariables(contextDir, contextName, config, context, contextState, parameters, dockerComposeFiles)
+}
@@ -0,0 +1,78 @@
+package kafkaCommand
+
+import (
+  "flag"
+  "fmt"
+  "strings"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/kafka"
+  "devlab/lib/logger"
+)
+
+/**
+* devlab kafka topics <context> [--apply] [--dry-run]: shows differences between topics declared in settings.yml
+* (kafka.topics section) and topics of broker, with --apply creates and alters topics
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("kafka", flag.ExitOnError)
+  apply := flags.Bool("apply", false, "create and alter topics to match settings.yml")
+  dryRun := flags.Bool("dry-run", false, "print commands instead of running them")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 2 || params[0] != "topics" {
+    logger.Text("Usage: devlab kafka topics <context> [--apply] [--dry-run]")
+    return
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  target, err := deploy.LoadTarget(config, params[1])
+  if errors.CheckAndReturnIfError(err) { return }
+  target.DryRun = *dryRun
+
+  backend, err := deploy.New(target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  changes, err := kafka.Changes(backend, target.Context)
+  if errors.CheckAndReturnIfError(err) { return }
+  Print(changes)
+
+  if *apply {
+    err = kafka.Apply(changes, kafka.Exec(backend))
+    if errors.CheckAndReturnIfError(err) { return }
+    logger.Info("Topics are provisioned\n")
+  }
+
+  return
+}
+
+/**
+* Prints changes of topics
+*/
+func Print(changes []*kafka.Change) {
+  if len(changes) == 0 {
+    logger.Text("There are no topics")
+    return
+  }
+
+  width := 0
+  for _, change := range changes {
+    if len(change.Topic) > width {
+      width = len(change.Topic)
+    }
+  }
+
+  for _, change := range changes {
+    line := fmt.Sprintf("%-*s  %-10s  %s", width, change.Topic, change.Action, strings.Join(change.Details, "; "))
+    switch change.Action {
+    case kafka.ACTION_CONFLICT:
+      logger.Warn("%s\n", line)
+    default:
+      logger.Text(strings.TrimRight(line, " "))
+    }
+  }
+}
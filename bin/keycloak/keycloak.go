@@ -0,0 +1,84 @@
+package keycloakCommand
+
+import (
+  "flag"
+  "fmt"
+  "strconv"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/keycloak"
+  "devlab/lib/logger"
+  "devlab/lib/util"
+)
+
+const USAGE = "Usage: devlab keycloak token <user> [--context <context>] [--client <client>] | apply [--context <context>] [--dry-run]"
+
+/**
+* devlab keycloak token <user> [--context <context>] [--client <client>]: prints access token of test user declared
+* in settings.yml (keycloak-users section) issued for client (the first one by default) for manual api testing.
+* devlab keycloak apply [--context <context>] [--dry-run]: provisions realm of context without 'devlab up'.
+* Context could be omitted if there is only one context
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("keycloak", flag.ExitOnError)
+  contextName := flags.String("context", "", "context of keycloak")
+  clientId := flags.String("client", "", "client which token is issued for")
+  dryRun := flags.Bool("dry-run", false, "print commands instead of running them")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if !(len(params) == 2 && params[0] == "token") && !(len(params) == 1 && params[0] == "apply") {
+    logger.Text(USAGE)
+    return
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if *contextName == "" {
+    *contextName, err = util.OnlyContext(config["contexts-path"], "devlab keycloak ... --context <context>")
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+
+  target, err := deploy.LoadTarget(config, *contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+  target.DryRun = *dryRun
+
+  if params[0] == "apply" {
+    backend, err := deploy.New(target)
+    if errors.CheckAndReturnIfError(err) { return err }
+
+    err = keycloak.Apply(backend, target)
+    if errors.CheckAndReturnIfError(err) { return err }
+    logger.Info("Realm is provisioned\n")
+    return nil
+  }
+
+  realm, err := keycloak.Declared(target.Context)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  user, err := realm.User(params[1])
+  if errors.CheckAndReturnIfError(err) { return }
+
+  realmClient, err := realm.Client(*clientId)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  hostPort, ok := target.State.Ports[keycloak.SERVICE][strconv.Itoa(keycloak.PORT)]
+  if !ok {
+    err = fmt.Errorf("context '%s' has no keycloak: enable 'keycloak' in system-services section of settings.yml and run 'devlab create-docker-compose %s'", *contextName, *contextName)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  token, err := keycloak.Token(fmt.Sprintf("http://localhost:%d", hostPort), realm.Name, realmClient, user)
+  if err != nil {
+    err = fmt.Errorf("%s (is context up? run 'devlab up %s --wait')", err, *contextName)
+  }
+  if errors.CheckAndReturnIfError(err) { return }
+
+  /* token is printed alone, so it could be used as $(devlab keycloak token <user>) */
+  fmt.Println(token)
+
+  return
+}
@@ -0,0 +1,64 @@
+package kibanaCommand
+
+import (
+  "flag"
+  "fmt"
+  "path/filepath"
+  "strconv"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/errors"
+  "devlab/lib/exec"
+  "devlab/lib/kibana"
+  "devlab/lib/logger"
+  "devlab/lib/state"
+)
+
+/**
+* devlab kibana <context> [--no-browser]: provisions index pattern of logs shipped by observability stack
+* and opens kibana discover with logs of context
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("kibana", flag.ExitOnError)
+  noBrowser := flags.Bool("no-browser", false, "print url of kibana instead of opening it")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 1 {
+    logger.Text("Usage: devlab kibana <context> [--no-browser]")
+    return
+  }
+  contextName := params[0]
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  contextState, err := state.Load(filepath.Join(config["contexts-path"], contextName))
+  if errors.CheckAndReturnIfError(err) { return }
+
+  hostPort, ok := contextState.Ports["kibana"][strconv.Itoa(kibana.PORT)]
+  if !ok {
+    err = fmt.Errorf("context '%s' has no kibana: set 'observability: elk' in settings.yml and run 'devlab create-docker-compose %s'", contextName, contextName)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+  kibanaUrl := fmt.Sprintf("http://localhost:%d", hostPort)
+
+  err = kibana.ProvisionIndexPattern(kibanaUrl)
+  if err != nil {
+    err = fmt.Errorf("%s (is context up? run 'devlab up %s --wait')", err, contextName)
+  }
+  if errors.CheckAndReturnIfError(err) { return }
+
+  discoverUrl := kibana.DiscoverUrl(kibanaUrl, contextName)
+  if *noBrowser {
+    fmt.Println(discoverUrl)
+    return
+  }
+
+  logger.Info("Opening %s\n", discoverUrl)
+  if err = exec.Browser(discoverUrl); err != nil {
+    logger.Warn("couldn't open browser, open the url manually\n")
+  }
+
+  return
+}
@@ -0,0 +1,349 @@
+package libraryCommand
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/library"
+  "devlab/lib/logger"
+  "devlab/lib/secrets"
+  "devlab/lib/util"
+)
+
+var input = bufio.NewScanner(os.Stdin)
+
+/**
+* devlab library list | show <component> | validate | add <component> --context <context> | new <name> |
+* update --context <context>
+*/
+func Call(commandArgs []string) (err error) {
+  if len(commandArgs) == 0 {
+    return usage()
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  switch commandArgs[0] {
+  case "list":
+    return List(config)
+  case "show":
+    if len(commandArgs) != 2 { return usage() }
+    return Show(config, commandArgs[1])
+  case "validate":
+    return Validate(config)
+  case "add":
+    return add(config, commandArgs[1:])
+  case "new":
+    return create(config["library-path"], commandArgs[1:])
+  case "update":
+    return update(config, commandArgs[1:])
+  }
+
+  return usage()
+}
+
+/**
+* Prints components of library with their descriptions
+*/
+func List(config map[string]string) (err error) {
+  componentsLibrary, err := loadLibrary(config)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  width := 0
+  for _, name := range componentsLibrary.Names() {
+    if len(name) > width {
+      width = len(name)
+    }
+  }
+
+  for _, name := range componentsLibrary.Names() {
+    component := componentsLibrary.Components[name]
+    line := fmt.Sprintf("%-*s  %s", width, name, component.Description)
+    if len(component.Extensions) > 0 {
+      line += " (extensions: " + strings.Join(util.SortedKeys(component.Extensions), ", ") + ")"
+    }
+    logger.Text(line)
+  }
+
+  return
+}
+
+/**
+* Prints manifest of component: services, parameters, files, health checks, dependencies and extensions
+*/
+func Show(config map[string]string, name string) (err error) {
+  componentsLibrary, err := loadLibrary(config)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  component := componentsLibrary.Find(name)
+  if component == nil {
+    err = fmt.Errorf("component '%s' is not found in library, see 'devlab library list'", name)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  logger.Header(strings.ToUpper(component.Name))
+  logger.Text(component.Description)
+  logger.Text("folder: " + component.Dir)
+  logger.Text("services: " + strings.Join(component.Services, ", "))
+  if len(component.Dependencies) > 0 {
+    logger.Text("dependencies: " + strings.Join(component.Dependencies, ", "))
+  }
+  if len(component.Files) > 0 {
+    logger.Text("files: " + strings.Join(component.Files, ", "))
+  }
+
+  if len(component.Parameters) > 0 {
+    logger.Header("PARAMETERS")
+    for _, parameterName := range util.SortedKeys(component.Parameters) {
+      logger.Text(parameterName + ": " + describeParameter(component.Parameters[parameterName]))
+    }
+  }
+
+  if len(component.Healthchecks) > 0 {
+    logger.Header("HEALTH CHECKS")
+    for _, serviceName := range util.SortedKeys(component.Healthchecks) {
+      logger.Text(serviceName + ": " + component.Healthchecks[serviceName].String())
+    }
+  }
+
+  if len(component.Extensions) > 0 {
+    logger.Header("EXTENSIONS")
+    for _, extensionName := range util.SortedKeys(component.Extensions) {
+      extension := component.Extensions[extensionName]
+      logger.Text(fmt.Sprintf("%s: %s (services: %s)", extensionName, extension.Description, strings.Join(extension.Services, ", ")))
+    }
+  }
+
+  return
+}
+
+/**
+* Checks manifests of all components of library
+*/
+func Validate(config map[string]string) (err error) {
+  componentsLibrary, err := loadLibrary(config)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if err = componentsLibrary.Validate(); err != nil {
+    logger.Warn("%s\n", err)
+    os.Exit(1)
+  }
+  logger.Info("%d components are valid\n", len(componentsLibrary.Components))
+
+  return
+}
+
+/**
+* devlab library add <component> --context <context> [--extensions <a,b>] [--non-interactive]:
+* enables component in system-services of settings.yml of context asking values of its parameters
+*/
+func add(config map[string]string, commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("library add", flag.ExitOnError)
+  contextName := flags.String("context", "", "context to add component to")
+  extensions := flags.String("extensions", "", "extensions of component to enable (comma separated)")
+  nonInteractive := flags.Bool("non-interactive", false, "do not ask anything, take default values of parameters")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 1 || *contextName == "" {
+    logger.Text("Usage: devlab library add <component> --context <context> [--extensions <a,b>] [--non-interactive]")
+    return
+  }
+
+  contextDir := filepath.Join(config["contexts-path"], *contextName)
+  componentsLibrary, err := library.ForContext(config, contextDir)
+  if errors.CheckAndReturnIfError(err) { return }
+  printWarnings(componentsLibrary)
+
+  component := componentsLibrary.Find(params[0])
+  if component == nil {
+    err = fmt.Errorf("component '%s' is not found in library, see 'devlab library list'", params[0])
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+  for _, extension := range library.Extensions(map[string]string{"extensions": *extensions}) {
+    if _, ok := component.Extensions[extension]; !ok {
+      err = fmt.Errorf("component '%s' has no extension '%s'", component.Name, extension)
+      errors.CheckAndReturnIfError(err)
+      return
+    }
+  }
+
+  contextSettings := contextDir + "/settings.yml"
+  context, err := files.ReadContextConfig(contextSettings)
+  if errors.CheckAndReturnIfError(err) { return }
+  current := context["system-services"][component.Name]
+
+  values := map[string]string{}
+  for _, name := range util.SortedKeys(component.Parameters) {
+    parameter := component.Parameters[name]
+    value := current[name]
+    if value == "" {
+      value = parameter.Default
+    }
+    if value == "" && parameter.Secret {
+      value = secrets.REFERENCE_PREFIX + component.Name + "-" + name
+    }
+
+    if !*nonInteractive {
+      value = ask(name + " (" + parameter.Description + ")", value)
+    }
+    if value == "" && parameter.Required {
+      err = fmt.Errorf("parameter '%s' of component '%s' is required", name, component.Name)
+      errors.CheckAndReturnIfError(err)
+      return
+    }
+    if parameter.Secret && !secrets.IsReference(value) {
+      logger.Warn("parameter '%s' is secret, its value should be secret reference (%s<name>)\n", name, secrets.REFERENCE_PREFIX)
+    }
+    values[name] = value
+  }
+
+  path := []string{"system-services", component.Name}
+  if err = files.UpdateYaml(contextSettings, append(path, "enabled"), true); errors.CheckAndReturnIfError(err) { return }
+  if *extensions != "" {
+    if err = files.UpdateYaml(contextSettings, append(path, "extensions"), *extensions); errors.CheckAndReturnIfError(err) { return }
+  }
+  for _, name := range util.SortedKeys(values) {
+    if values[name] == "" { continue }
+    if err = files.UpdateYaml(contextSettings, append(path, name), values[name]); errors.CheckAndReturnIfError(err) { return }
+  }
+  logger.Info("Component '%s' is added to %s\n", component.Name, contextSettings)
+
+  for _, name := range util.SortedKeys(values) {
+    if !secrets.IsReference(values[name]) { continue }
+    if _, err := secrets.Resolve(values[name]); err != nil {
+      logger.Warn("%s\n", err)
+    }
+  }
+  if len(component.Dependencies) > 0 {
+    logger.Text("Dependencies are added to context too: " + strings.Join(component.Dependencies, ", "))
+  }
+  logger.Text("Run 'devlab create-docker-compose " + *contextName + "' to update docker compose files of context")
+
+  return
+}
+
+/**
+* devlab library new <name> [--image <image>] [--port <port>]: scaffolds folder of new component in library
+*/
+func create(libraryPath string, commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("library new", flag.ExitOnError)
+  image := flags.String("image", "", "docker image of service of component (name of component by default)")
+  port := flags.Int("port", 0, "port exposed by service of component")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 1 {
+    logger.Text("Usage: devlab library new <name> [--image <image>] [--port <port>]")
+    return
+  }
+
+  componentDir, err := library.Scaffold(libraryPath, params[0], *image, *port)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  logger.Info("Component '%s' is created in %s\n", params[0], componentDir)
+  logger.Text("Describe it in " + library.COMPONENT_MANIFEST + ", adjust " + library.COMPONENT_COMPOSE + " and run 'devlab library validate'")
+
+  return
+}
+
+/**
+* devlab library update --context <context>: resolves library sources of context again and updates its lock file
+*/
+func update(config map[string]string, commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("library update", flag.ExitOnError)
+  contextName := flags.String("context", "", "context to update lock file of")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) != 0 || *contextName == "" {
+    logger.Text("Usage: devlab library update --context <context>")
+    return
+  }
+
+  contextDir := filepath.Join(config["contexts-path"], *contextName)
+  if isExists, _ := files.IsExists(contextDir); !isExists {
+    err = fmt.Errorf("context '%s' is not found", *contextName)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  componentsLibrary, err := library.ResolveForContext(config, contextDir, true)
+  if errors.CheckAndReturnIfError(err) { return }
+  printWarnings(componentsLibrary)
+
+  lock, err := library.ReadLock(contextDir)
+  if errors.CheckAndReturnIfError(err) { return }
+  for _, source := range lock.Sources {
+    line := source.String() + " (" + source.Type + ")"
+    if source.Resolved != "" {
+      line += " " + source.Resolved
+    }
+    logger.Text(line)
+  }
+  logger.Info("%s is updated, run 'devlab create-docker-compose %s' to use updated components\n", contextDir + "/" + library.LOCK_FILE, *contextName)
+
+  return
+}
+
+/**
+* Loads library of latest versions of sources, prints conflicts of components
+*/
+func loadLibrary(config map[string]string) (componentsLibrary *library.Library, err error) {
+  if componentsLibrary, err = library.ForConfig(config); err != nil { return }
+  printWarnings(componentsLibrary)
+
+  return
+}
+
+func printWarnings(componentsLibrary *library.Library) {
+  for _, warning := range componentsLibrary.Warnings {
+    logger.Warn("%s\n", warning)
+  }
+}
+
+func describeParameter(parameter *library.Parameter) string {
+  description := parameter.Description
+  var attributes []string
+  if parameter.Default != "" {
+    attributes = append(attributes, "default " + parameter.Default)
+  }
+  if parameter.Required {
+    attributes = append(attributes, "required")
+  }
+  if parameter.Secret {
+    attributes = append(attributes, "secret")
+  }
+  if len(attributes) > 0 {
+    description += " (" + strings.Join(attributes, ", ") + ")"
+  }
+
+  return description
+}
+
+/**
+* Asks value, empty answer means default value
+*/
+func ask(question string, defaultValue string) string {
+  logger.Info("%s [%s]: ", question, defaultValue)
+  if !input.Scan() {
+    return defaultValue
+  }
+
+  if value := strings.TrimSpace(input.Text()); value != "" {
+    return value
+  }
+  return defaultValue
+}
+
+func usage() error {
+  logger.Text("Usage: devlab library list | show <component> | validate | add <component> --context <context> | new <name> | update --context <context>")
+  return nil
+}
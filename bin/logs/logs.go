@@ -0,0 +1,144 @@
+package logsCommand
+
+import (
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "regexp"
+  "sort"
+  "strings"
+  "sync"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/docker"
+  "devlab/lib/errors"
+  "devlab/lib/logger"
+  "devlab/lib/logs"
+  colorPrint "github.com/fatih/color"
+)
+
+/* Colours of service prefixes (services get them in order of names) */
+var prefixColors = []colorPrint.Attribute{colorPrint.FgCyan, colorPrint.FgGreen, colorPrint.FgYellow, colorPrint.FgBlue, colorPrint.FgMagenta, colorPrint.FgRed}
+
+/**
+* devlab logs <context> [service...] [--since <duration|time>] [--grep <regex>] [--level <level>] [--output <file>]
+* [--follow=false]: streams logs of services of context (all services if they are not set)
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("logs", flag.ExitOnError)
+  since := flags.String("since", "", "show lines written after time (RFC3339) or during duration before now (e.g. 10m)")
+  grep := flags.String("grep", "", "show lines matching regular expression")
+  level := flags.String("level", "", "minimal level of json lines: trace, debug, info, warn, error, fatal")
+  output := flags.String("output", "", "write lines to file instead of terminal")
+  follow := flags.Bool("follow", true, "keep streaming new lines, reconnecting to restarted services")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) == 0 {
+    logger.Text("Usage: devlab logs <context> [service...] [--since <duration|time>] [--grep <regex>] [--level <level>] [--output <file>] [--follow=false]")
+    return
+  }
+  contextName := params[0]
+
+  filter := &logs.Filter{}
+  if *grep != "" {
+    filter.Grep, err = regexp.Compile(*grep)
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+  if *level != "" {
+    filter.Level, err = logs.ParseLevel(*level)
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+  sinceTime, err := logs.ParseSince(*since)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  target, err := deploy.LoadTarget(config, contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  serviceNames, err := selectServices(target, params[1:])
+  if errors.CheckAndReturnIfError(err) { return }
+
+  client, err := docker.NewClient()
+  if errors.CheckAndReturnIfError(err) { return }
+  project := target.Project()
+  _, err = client.Containers(project)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  var writer io.Writer = os.Stdout
+  isColored := true
+  if *output != "" {
+    file, err := os.Create(*output)
+    if errors.CheckAndReturnIfError(err) { return err }
+    defer file.Close()
+    writer, isColored = file, false
+  }
+
+  width := 0
+  for _, serviceName := range serviceNames {
+    if len(serviceName) > width {
+      width = len(serviceName)
+    }
+  }
+
+  var mutex sync.Mutex
+  var waitGroup sync.WaitGroup
+  for i, serviceName := range serviceNames {
+    prefix := fmt.Sprintf("%-*s | ", width, serviceName)
+    if isColored {
+      prefix = colorPrint.New(prefixColors[i % len(prefixColors)]).Sprint(prefix)
+    }
+
+    waitGroup.Add(1)
+    go func(serviceName string, prefix string) {
+      defer waitGroup.Done()
+      err := logs.Follow(client, project, serviceName, sinceTime, *follow, func(line logs.Line) {
+        if !filter.Match(line.Text) { return }
+
+        mutex.Lock()
+        defer mutex.Unlock()
+        fmt.Fprintln(writer, prefix + line.Text)
+      })
+      if err != nil {
+        logger.Warn("%s: %s\n", serviceName, err)
+      }
+    }(serviceName, prefix)
+  }
+  waitGroup.Wait()
+
+  return
+}
+
+/**
+* Returns services to stream logs of: set ones (they should be defined in generated docker compose files)
+* or all services of context
+*/
+func selectServices(target *deploy.Target, requested []string) (serviceNames []string, err error) {
+  defined := target.ServiceNames()
+  isDefined := make(map[string]bool)
+  for _, serviceName := range defined {
+    isDefined[serviceName] = true
+  }
+
+  if len(requested) == 0 {
+    requested = defined
+  }
+
+  var unknown []string
+  for _, serviceName := range requested {
+    if isDefined[serviceName] {
+      serviceNames = append(serviceNames, serviceName)
+    } else {
+      unknown = append(unknown, serviceName)
+    }
+  }
+  if len(unknown) > 0 {
+    return nil, fmt.Errorf("services are not defined in context '%s': %s", target.ContextName, strings.Join(unknown, ", "))
+  }
+
+  sort.Strings(serviceNames)
+  return
+}
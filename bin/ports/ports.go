@@ -0,0 +1,60 @@
+package portsCommand
+
+import (
+  "fmt"
+  "path/filepath"
+  "sort"
+  "devlab/lib/config"
+  "devlab/lib/errors"
+  "devlab/lib/logger"
+  "devlab/lib/ports"
+  "devlab/lib/state"
+)
+
+/**
+* devlab ports <context>: prints host ports allocated for services of context
+*/
+func Call(commandArgs []string) (err error) {
+  if len(commandArgs) != 1 {
+    logger.Text("Usage: devlab ports <context>")
+    return
+  }
+  contextName := commandArgs[0]
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  contextState, err := state.Load(filepath.Join(config["contexts-path"], contextName))
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if len(contextState.Ports) == 0 {
+    logger.Text("There are no allocated ports, run 'devlab create-docker-compose " + contextName + "'")
+    return
+  }
+
+  serviceNames := make([]string, 0, len(contextState.Ports))
+  for serviceName := range contextState.Ports {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  fmt.Printf("%-32s %-16s %-24s %s\n", "SERVICE", "CONTAINER PORT", "HOST", "HOST PORT STATE")
+  for _, serviceName := range serviceNames {
+    containerPorts := make([]string, 0, len(contextState.Ports[serviceName]))
+    for containerPort := range contextState.Ports[serviceName] {
+      containerPorts = append(containerPorts, containerPort)
+    }
+    sort.Strings(containerPorts)
+
+    for _, containerPort := range containerPorts {
+      hostPort := contextState.Ports[serviceName][containerPort]
+      portState := "listening"
+      if ports.IsFree(hostPort) {
+        portState = "free"
+      }
+      fmt.Printf("%-32s %-16s %-24s %s\n", serviceName, containerPort, fmt.Sprintf("localhost:%d", hostPort), portState)
+    }
+  }
+
+  return
+}
@@ -0,0 +1,87 @@
+package secretCommand
+
+import (
+  "bufio"
+  "fmt"
+  "os"
+  "sort"
+  "strings"
+  "devlab/lib/errors"
+  "devlab/lib/logger"
+  "devlab/lib/secrets"
+)
+
+/**
+* devlab secret set <name> | get <name> | list | rm <name>
+* (value of 'set' is read from stdin, so it doesn't get into shell history)
+*/
+func Call(commandArgs []string) (err error) {
+  if len(commandArgs) == 0 {
+    return usage()
+  }
+
+  switch commandArgs[0] {
+  case "set":
+    if len(commandArgs) != 2 { return usage() }
+    return Set(commandArgs[1])
+  case "get":
+    if len(commandArgs) != 2 { return usage() }
+    value, err := secrets.Resolve(secrets.REFERENCE_PREFIX + commandArgs[1])
+    if errors.CheckAndReturnIfError(err) { return err }
+    fmt.Println(value)
+  case "list":
+    return List()
+  case "rm":
+    if len(commandArgs) != 2 { return usage() }
+    err = secrets.Delete(commandArgs[1])
+    if errors.CheckAndReturnIfError(err) { return }
+    logger.Info("Secret '%s' is deleted\n", commandArgs[1])
+  default:
+    return usage()
+  }
+
+  return
+}
+
+/**
+* Reads secret value from stdin and saves it in store
+*/
+func Set(name string) (err error) {
+  logger.Text("Value of secret '" + name + "':")
+  value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+  value = strings.TrimRight(value, "\r\n")
+  if value == "" {
+    err = fmt.Errorf("value of secret '%s' is empty", name)
+  } else {
+    err = secrets.Set(name, value)
+  }
+  if errors.CheckAndReturnIfError(err) { return }
+
+  logger.Info("Secret '%s' is saved in %s\n", name, secrets.StorePath())
+  return
+}
+
+/**
+* Prints names of secrets in store (values are not printed)
+*/
+func List() (err error) {
+  store, err := secrets.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  names := make([]string, 0, len(store))
+  for name := range store {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  for _, name := range names {
+    fmt.Println(name)
+  }
+
+  return
+}
+
+func usage() error {
+  logger.Text("Usage: devlab secret set <name> | get <name> | list | rm <name>")
+  return nil
+}
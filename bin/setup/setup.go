@@ -0,0 +1,353 @@
+package setup
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "regexp"
+  "strconv"
+  "strings"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/errors"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/library"
+  "devlab/lib/logger"
+  "devlab/lib/ports"
+  "devlab/lib/yml"
+)
+
+const SSH_KEY_PATH_FILE = ".ssh-key-path"
+
+type tool struct {
+  name string
+  versionCommands []string
+  minVersion string
+}
+
+/* Tools devlab depends on with the minimal supported versions */
+var requiredTools = []tool{
+  {"git", []string{"git --version"}, "2.0"},
+  {"docker", []string{"docker --version"}, "18.06"},
+  {"docker compose", []string{"docker compose version", "docker-compose version"}, "1.21"},
+}
+
+/* Validators of .config values, key without validator accepts any value */
+var validators = map[string]func(value string) error{
+  "data-path": isExistingDir,
+  "library-path": isExistingDir,
+  "library-sources": isLibrarySources,
+  "contexts-path": isNotEmpty,
+  "base-branch": isBranchName,
+  "docker-compose-version": isComposeVersion,
+  "github-repository-path": isRepositoryPath,
+  "ports-range": isPortsRange,
+  "deploy-strategy": isDeployStrategy,
+}
+
+var input = bufio.NewScanner(os.Stdin)
+
+/* Folder with .config.example, .config is written there and paths of .config are relative to it */
+var projectRoot string
+
+/**
+* Creates .config from .config.example: every key is taken from flag (--<key>) or environment variable
+* (DEVLAB_<KEY>), otherwise it is asked with value of existing .config or .config.example as default.
+* Keys set in global config and not in project .config are not written, they stay global
+*/
+func Call(args []string) (err error) {
+  logger.Header("DEVLAB SETUP")
+
+  projectRoot, err = config.FindProjectRoot()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  exampleData, err := files.ReadTextFile(filepath.Join(projectRoot, config.PROJECT_CONFIG_EXAMPLE_FILE))
+  if errors.CheckAndReturnIfError(err) { return }
+
+  keys := yml.OneLevelYAMLKeys(exampleData)
+  defaults, err := yml.ParseOneLevelYAML(exampleData)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  flags := flag.NewFlagSet("setup", flag.ContinueOnError)
+  nonInteractive := flags.Bool("non-interactive", false, "do not ask anything, take values from flags, environment and defaults")
+  skipChecks := flags.Bool("skip-checks", false, "do not check git, docker and docker compose versions")
+  sshKeyPath := flags.String("ssh-key", os.Getenv(config.ENV_PREFIX + "SSH_KEY_PATH"), "path to ssh key used for git")
+  if err = flags.Parse(args); err != nil { return }
+
+  if !*skipChecks {
+    if err = CheckTools(); err != nil {
+      logger.Warn("%s\n", err)
+      logger.Text("Install or upgrade required tools or run 'devlab setup --skip-checks'")
+      os.Exit(1)
+    }
+  }
+
+  /* answers are pre-filled from existing project .config, environment variables and flags are answers */
+  configPath := filepath.Join(projectRoot, config.PROJECT_CONFIG_FILE)
+  projectValues, err := readProjectConfig(configPath)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  values, err := config.LoadWithOrigins()
+  if _, isNotFound := err.(*files.MainConfigNotFoundError); err != nil && !isNotFound {
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  result := make(map[string]string)
+  var projectConfig yml.Tree
+  for _, key := range keys {
+    layered := values[key]
+    value, isProjectValue := projectValues[key]
+    if !isProjectValue {
+      value = defaults[key]
+    }
+
+    switch {
+    case layered.Origin == config.ORIGIN_ENV || layered.Origin == config.ORIGIN_FLAG:
+      value = layered.Value
+      if err = validate(key, value); err != nil {
+        logger.Warn("'%s': %s\n", key, err)
+        os.Exit(1)
+      }
+
+    /* value of global config is not copied to project .config, so it still could be changed globally */
+    case layered.Origin == config.ORIGIN_GLOBAL && !isProjectValue:
+      logger.Text(fmt.Sprintf("%s: %s (%s)", key, layered.Value, layered.Source))
+      result[key] = layered.Value
+      continue
+
+    case *nonInteractive:
+      if err = validate(key, value); err != nil {
+        logger.Warn("'%s': %s\n", key, err)
+        os.Exit(1)
+      }
+
+    default:
+      value = ask(key, value)
+    }
+
+    result[key] = value
+    projectConfig = append(projectConfig, yml.Pair{Key: key, Value: value})
+  }
+
+  err = files.WriteYaml(configPath, projectConfig)
+  if errors.CheckAndReturnIfError(err) { return }
+  logger.Info("%s has been written\n", configPath)
+
+  if *sshKeyPath == "" && !*nonInteractive {
+    home, _ := os.UserHomeDir()
+    *sshKeyPath = ask("ssh-key", filepath.Join(home, ".ssh", "id_rsa"))
+  }
+  if *sshKeyPath != "" {
+    err = files.WriteFileAtomic(filepath.Join(projectRoot, SSH_KEY_PATH_FILE), *sshKeyPath + "\n")
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+
+  if !*nonInteractive {
+    loginToRegistries(result)
+  }
+
+  logger.Info("Please, review '%s/default-context.yml': it is used as settings.yml of every new context\n", result["data-path"])
+
+  return
+}
+
+/**
+* Returns values of project .config (empty if there is no .config yet)
+*/
+func readProjectConfig(configPath string) (map[string]string, error) {
+  isExists, _ := files.IsExists(configPath)
+  if !isExists { return make(map[string]string), nil }
+
+  data, err := files.ReadTextFile(configPath)
+  if err != nil { return nil, err }
+
+  values, err := yml.ParseOneLevelYAML(data)
+  if err != nil { return nil, fmt.Errorf("%s: %s", configPath, err) }
+
+  return values, nil
+}
+
+/**
+* Checks that git, docker and docker compose are installed and have supported versions
+*/
+func CheckTools() error {
+  var problems []string
+
+  for _, t := range requiredTools {
+    version := ""
+    for _, command := range t.versionCommands {
+      out, err := exec.Command(command + " 2>/dev/null")
+      if err == nil {
+        version = parseVersion(out)
+        break
+      }
+    }
+
+    switch {
+    case version == "":
+      problems = append(problems, t.name + " is not installed")
+    case compareVersions(version, t.minVersion) < 0:
+      problems = append(problems, fmt.Sprintf("%s %s is not supported, %s or newer is required", t.name, version, t.minVersion))
+    default:
+      logger.Text(t.name + " " + version)
+    }
+  }
+
+  if len(problems) > 0 {
+    return fmt.Errorf("%s", strings.Join(problems, "; "))
+  }
+
+  return nil
+}
+
+/**
+* Asks value until it is valid, empty answer means default value
+*/
+func ask(key string, defaultValue string) string {
+  for {
+    logger.Info("%s [%s]: ", key, defaultValue)
+    if !input.Scan() {
+      return defaultValue
+    }
+
+    value := strings.TrimSpace(input.Text())
+    if value == "" {
+      value = defaultValue
+    }
+
+    err := validate(key, value)
+    if err == nil {
+      return value
+    }
+    logger.Warn("%s\n", err)
+  }
+}
+
+/**
+* Suggests to login to private npm registry and docker registry
+*/
+func loginToRegistries(config map[string]string) {
+  logger.Info("Would you like to login to private npm registry, y|N ? ")
+  if input.Scan() && strings.ToLower(strings.TrimSpace(input.Text())) == "y" {
+    logger.Info("npm registry url: ")
+    input.Scan()
+    registry := strings.TrimSpace(input.Text())
+    if registry != "" {
+      errors.CheckAndReturnIfError(exec.Interactive("npm login --registry=" + registry))
+    }
+  }
+
+  registryHost := strings.TrimSuffix(config["docker-registry-host"], "/")
+  if registryHost == "" { return }
+
+  logger.Info("Would you like to login to docker registry %s, y|N ? ", registryHost)
+  if input.Scan() && strings.ToLower(strings.TrimSpace(input.Text())) == "y" {
+    errors.CheckAndReturnIfError(exec.Interactive("docker login " + registryHost))
+  }
+}
+
+func validate(key string, value string) error {
+  if key == "ssh-key" {
+    return isExistingFile(value)
+  }
+
+  if validator, ok := validators[key]; ok {
+    return validator(value)
+  }
+
+  return nil
+}
+
+func isNotEmpty(value string) error {
+  if value == "" {
+    return fmt.Errorf("value should not be empty")
+  }
+  return nil
+}
+
+func isExistingDir(value string) error {
+  if err := isNotEmpty(value); err != nil { return err }
+
+  path := value
+  if !filepath.IsAbs(path) {
+    path = filepath.Join(projectRoot, path)
+  }
+  info, err := os.Stat(path)
+  if err != nil || !info.IsDir() {
+    return fmt.Errorf("directory '%s' does not exist", value)
+  }
+  return nil
+}
+
+func isExistingFile(value string) error {
+  if _, err := os.Stat(value); err != nil {
+    return fmt.Errorf("file '%s' does not exist", value)
+  }
+  return nil
+}
+
+func isBranchName(value string) error {
+  if value == "" || strings.ContainsAny(value, " ~^:?*[\\") {
+    return fmt.Errorf("'%s' is not valid git branch name", value)
+  }
+  return nil
+}
+
+func isComposeVersion(value string) error {
+  _, err := DockerComposeFileBuilder.ParseFileVersion(value)
+  return err
+}
+
+func isPortsRange(value string) error {
+  _, err := ports.ParseRange(value)
+  return err
+}
+
+func isDeployStrategy(value string) error {
+  for _, strategy := range deploy.Strategies {
+    if value == strategy { return nil }
+  }
+  return fmt.Errorf("'%s' is not supported deploy strategy (%s)", value, strings.Join(deploy.Strategies, ", "))
+}
+
+func isLibrarySources(value string) error {
+  _, err := library.ParseSources(value, "")
+  return err
+}
+
+func isRepositoryPath(value string) error {
+  if !strings.HasSuffix(value, "/") && !strings.HasSuffix(value, ":") {
+    return fmt.Errorf("'%s' should end with '/' or ':' (service repository name is appended to it)", value)
+  }
+  return nil
+}
+
+/**
+* Extracts first version number ('x.y' or 'x.y.z') from command output
+*/
+func parseVersion(out string) string {
+  return regexp.MustCompile(`\d+\.\d+(\.\d+)?`).FindString(out)
+}
+
+/**
+* Compares versions component by component, returns -1, 0 or 1
+*/
+func compareVersions(a string, b string) int {
+  aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+  for i := 0; i < len(aParts) || i < len(bParts); i++ {
+    var aNum, bNum int
+    if i < len(aParts) { aNum, _ = strconv.Atoi(aParts[i]) }
+    if i < len(bParts) { bNum, _ = strconv.Atoi(bParts[i]) }
+
+    if aNum != bNum {
+      if aNum < bNum { return -1 }
+      return 1
+    }
+  }
+  return 0
+}
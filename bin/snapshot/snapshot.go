@@ -0,0 +1,214 @@
+package snapshotCommand
+
+import (
+  "flag"
+  "fmt"
+  "strings"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/errors"
+  "devlab/lib/logger"
+  "devlab/lib/snapshot"
+  "devlab/lib/util"
+)
+
+const USAGE = "Usage: devlab snapshot create|list|restore|delete <context> [name] [--yes]"
+
+/**
+* devlab snapshot create|list|restore|delete <context> [name] [--yes]: saves data of system services of context
+* (named volumes and bind-mounted folders) with git revisions of application services and restores it
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+  yes := flags.Bool("yes", false, "do not ask confirmation of restore and delete")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) < 2 || len(params) > 3 {
+    logger.Text(USAGE)
+    return
+  }
+  command, contextName, name := params[0], params[1], ""
+  if len(params) == 3 {
+    name = params[2]
+  }
+  if (command == "restore" || command == "delete") && name == "" {
+    logger.Text(USAGE)
+    return
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  target, err := deploy.LoadTarget(config, contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  switch command {
+  case "create":
+    err = create(target, name)
+  case "list":
+    err = list(target)
+  case "restore":
+    err = restore(target, name, *yes)
+  case "delete":
+    err = remove(target, name, *yes)
+  default:
+    logger.Text(USAGE)
+  }
+  errors.CheckAndReturnIfError(err)
+
+  return
+}
+
+func create(target *deploy.Target, name string) error {
+  backend, err := compose(target)
+  if err != nil { return err }
+
+  volumes, err := snapshot.Volumes(target.DockerComposeFiles[0], target.Project(), target.ContextDir)
+  if err != nil { return err }
+  if len(volumes) == 0 {
+    logger.Text("There are no data volumes of system services")
+    return nil
+  }
+
+  revisions, err := snapshot.Revisions(target.DockerComposeFiles[1], target.ContextDir)
+  if err != nil { return err }
+  for _, serviceName := range util.SortedKeys(revisions) {
+    if revisions[serviceName].Dirty {
+      logger.Warn("service '%s' has not commited changes, they are not recorded in snapshot\n", serviceName)
+    }
+  }
+
+  /* services are stopped while their data is archived, so archives are consistent */
+  stopped, err := stop(backend, snapshot.ServiceNames(volumes))
+  if err != nil { return err }
+
+  created, warnings, err := snapshot.Create(target.ContextDir, target.ContextName, name, volumes, revisions)
+  if startErr := backend.Start(stopped...); err == nil {
+    err = startErr
+  }
+  if err != nil { return err }
+
+  for _, warning := range warnings {
+    logger.Warn("%s\n", warning)
+  }
+  logger.Info("Snapshot '%s' is created (%s)\n", created.Name, size(created.Size()))
+
+  return nil
+}
+
+func list(target *deploy.Target) error {
+  snapshots, err := snapshot.List(target.ContextDir)
+  if err != nil { return err }
+  if len(snapshots) == 0 {
+    logger.Text("There are no snapshots, see 'devlab snapshot create " + target.ContextName + "'")
+    return nil
+  }
+
+  for _, item := range snapshots {
+    var revisions []string
+    for _, serviceName := range util.SortedKeys(item.Services) {
+      revision := item.Services[serviceName]
+      revisions = append(revisions, fmt.Sprintf("%s@%s", serviceName, revision.Branch))
+    }
+    logger.Text(fmt.Sprintf("%-20s  %s  %8s  %s", item.Name, item.CreatedAt.Format("2006-01-02 15:04"), size(item.Size()), strings.Join(revisions, ", ")))
+  }
+
+  return nil
+}
+
+func restore(target *deploy.Target, name string, yes bool) error {
+  backend, err := compose(target)
+  if err != nil { return err }
+
+  restored, err := snapshot.Load(target.ContextDir, name)
+  if err != nil { return err }
+
+  revisions, err := snapshot.Revisions(target.DockerComposeFiles[1], target.ContextDir)
+  if err != nil { return err }
+  changes := restored.Changes(revisions)
+  for _, change := range changes {
+    logger.Warn("%s\n", change)
+  }
+  if len(changes) > 0 {
+    logger.Warn("services differ from snapshot, data could be incompatible with them\n")
+  }
+
+  if !yes && !util.Confirm(fmt.Sprintf("Data of %s of context '%s' will be replaced, continue, y|N ? ",
+    strings.Join(snapshot.ServiceNames(restored.Volumes), ", "), target.ContextName)) {
+    return nil
+  }
+
+  stopped, err := stop(backend, snapshot.ServiceNames(restored.Volumes))
+  if err != nil { return err }
+
+  err = restored.Restore(target.Project())
+  if startErr := backend.Start(stopped...); err == nil {
+    err = startErr
+  }
+  if err != nil { return err }
+
+  logger.Info("Snapshot '%s' is restored\n", restored.Name)
+  return nil
+}
+
+func remove(target *deploy.Target, name string, yes bool) error {
+  removed, err := snapshot.Load(target.ContextDir, name)
+  if err != nil { return err }
+
+  if !yes && !util.Confirm(fmt.Sprintf("Snapshot '%s' of context '%s' will be deleted, continue, y|N ? ", name, target.ContextName)) {
+    return nil
+  }
+  if err = removed.Delete(); err != nil { return err }
+
+  logger.Info("Snapshot '%s' is deleted\n", name)
+  return nil
+}
+
+/**
+* Returns docker compose backend of context (volumes of other strategies are not supported)
+*/
+func compose(target *deploy.Target) (*deploy.Compose, error) {
+  backend, err := deploy.New(target)
+  if err != nil { return nil, err }
+
+  composeBackend, ok := backend.(*deploy.Compose)
+  if !ok {
+    return nil, fmt.Errorf("snapshots are supported with '%s' deploy strategy only", deploy.STRATEGY_DOCKER_COMPOSE)
+  }
+
+  return composeBackend, nil
+}
+
+/**
+* Stops running services of serviceNames, returns stopped ones
+*/
+func stop(backend *deploy.Compose, serviceNames []string) (stopped []string, err error) {
+  running, err := backend.Running()
+  if err != nil { return }
+
+  isRunning := make(map[string]bool)
+  for _, serviceName := range running {
+    isRunning[serviceName] = true
+  }
+  for _, serviceName := range serviceNames {
+    if isRunning[serviceName] {
+      stopped = append(stopped, serviceName)
+    }
+  }
+
+  return stopped, backend.Stop(stopped...)
+}
+
+func size(bytes int64) string {
+  switch {
+  case bytes >= 1 << 30:
+    return fmt.Sprintf("%.1fG", float64(bytes) / (1 << 30))
+  case bytes >= 1 << 20:
+    return fmt.Sprintf("%.1fM", float64(bytes) / (1 << 20))
+  case bytes >= 1 << 10:
+    return fmt.Sprintf("%.1fK", float64(bytes) / (1 << 10))
+  }
+
+  return fmt.Sprintf("%dB", bytes)
+}
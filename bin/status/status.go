@@ -0,0 +1,237 @@
+package statusCommand
+
+import (
+  "encoding/json"
+  "flag"
+  "fmt"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+  "devlab/lib/args"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/docker"
+  "devlab/lib/errors"
+  "devlab/lib/logger"
+  "devlab/lib/services"
+  "devlab/lib/util"
+)
+
+/* Interval of refreshing status with --watch */
+const WATCH_INTERVAL = 2 * time.Second
+
+const SERVICE_TYPE_APPLICATION = "application"
+const SERVICE_TYPE_SYSTEM = "system"
+
+const HEALTH_HEALTHY = "healthy"
+const HEALTH_UNHEALTHY = "unhealthy"
+
+/**
+* Status of service of context
+*/
+type Row struct {
+  Service string `json:"service"`
+  Type string `json:"type"`
+  Git *services.GitState `json:"git"`
+  /* container state ('missing' if there is no container) */
+  State string `json:"state"`
+  StartedAt *time.Time `json:"startedAt"`
+  Restarts int `json:"restarts"`
+  Health string `json:"health"`
+  Ports []string `json:"ports"`
+  ImageTag string `json:"imageTag"`
+}
+
+/**
+* devlab status [context] [--watch] [--json]: prints git, container and health state of services of context
+* (context could be omitted if there is only one context)
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("status", flag.ExitOnError)
+  watch := flags.Bool("watch", false, "refresh status in place every " + WATCH_INTERVAL.String())
+  asJson := flags.Bool("json", false, "print status as json")
+  params, err := args.Parse(flags, commandArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+  if len(params) > 1 {
+    logger.Text("Usage: devlab status [context] [--watch] [--json]")
+    return
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  contextName := ""
+  if len(params) == 1 {
+    contextName = params[0]
+  } else {
+    contextName, err = util.OnlyContext(config["contexts-path"], "devlab status <context>")
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+
+  target, err := deploy.LoadTarget(config, contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  backend, err := deploy.New(target)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  client, err := docker.NewClient()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  for {
+    rows, err := Status(client, target, backend.Probe)
+    if errors.CheckAndReturnIfError(err) { return err }
+
+    if *watch && !*asJson {
+      /* cursor is moved home and screen is cleared, so table is refreshed in place */
+      fmt.Print("\033[H\033[2J")
+      logger.Text(fmt.Sprintf("Context '%s', %s", contextName, time.Now().Format("15:04:05")))
+    }
+
+    if *asJson {
+      data, _ := json.MarshalIndent(rows, "", "  ")
+      fmt.Println(string(data))
+    } else {
+      Print(rows)
+    }
+
+    if !*watch { return nil }
+    time.Sleep(WATCH_INTERVAL)
+  }
+}
+
+/**
+* Returns status of every application and system service of generated docker compose files of context.
+* Health of running service with health check (library component or settings.yml) is probed, health of other
+* services is health of their containers
+*/
+func Status(client docker.Client, target *deploy.Target, probe func(serviceName string) error) (rows []Row, err error) {
+  containers, err := client.Containers(target.Project())
+  if err != nil { return }
+
+  containersByService := make(map[string]docker.Container)
+  for _, container := range containers {
+    containersByService[container.Service] = container
+  }
+
+  /* generated files are read in order: system, application */
+  for i, dockerComposeData := range target.DockerComposeFiles {
+    serviceType := SERVICE_TYPE_SYSTEM
+    if i > 0 {
+      serviceType = SERVICE_TYPE_APPLICATION
+    }
+
+    serviceNames := make([]string, 0, len(dockerComposeData.Services))
+    for serviceName := range dockerComposeData.Services {
+      serviceNames = append(serviceNames, serviceName)
+    }
+    sort.Strings(serviceNames)
+
+    for _, serviceName := range serviceNames {
+      row := Row{Service: serviceName, Type: serviceType, State: "missing", ImageTag: imageTag(dockerComposeData.Services[serviceName].Image)}
+
+      if serviceType == SERVICE_TYPE_APPLICATION {
+        if row.Git, err = services.GitStatus(filepath.Join(target.ContextDir, "services"), serviceName); err != nil { return }
+      }
+
+      if container, ok := containersByService[serviceName]; ok {
+        row.State, row.Restarts, row.Health, row.ImageTag = container.State, container.RestartCount, container.Health, imageTag(container.Image)
+        if container.State == "running" {
+          startedAt := container.StartedAt
+          row.StartedAt = &startedAt
+
+          if _, isChecked := target.Checks[serviceName]; isChecked {
+            row.Health = HEALTH_HEALTHY
+            if probe(serviceName) != nil {
+              row.Health = HEALTH_UNHEALTHY
+            }
+          }
+        }
+        for _, port := range container.Ports {
+          if port.PublicPort == 0 { continue }
+          row.Ports = append(row.Ports, fmt.Sprintf("%d->%d/%s", port.PublicPort, port.PrivatePort, port.Type))
+        }
+        sort.Strings(row.Ports)
+        row.Ports = unique(row.Ports)
+      }
+
+      rows = append(rows, row)
+    }
+  }
+
+  return
+}
+
+/**
+* Prints status as table
+*/
+func Print(rows []Row) {
+  format := "%-32s %-12s %-24s %-8s %-6s %-10s %-10s %-9s %-10s %-24s %s\n"
+  fmt.Printf(format, "SERVICE", "TYPE", "BRANCH", "AHEAD", "DIRTY", "STATE", "UPTIME", "RESTARTS", "HEALTH", "PORTS", "IMAGE TAG")
+
+  for _, row := range rows {
+    branch, aheadBehind, dirty := "-", "-", "-"
+    if row.Git != nil {
+      branch, aheadBehind = row.Git.Branch, fmt.Sprintf("+%d/-%d", row.Git.Ahead, row.Git.Behind)
+      if row.Git.Dirty {
+        dirty = "*"
+      }
+    }
+
+    uptime := "-"
+    if row.StartedAt != nil {
+      uptime = formatDuration(time.Since(*row.StartedAt))
+    }
+
+    fmt.Printf(format, row.Service, row.Type, branch, aheadBehind, dirty, row.State, uptime, strconv.Itoa(row.Restarts),
+      valueOrDash(row.Health), valueOrDash(strings.Join(row.Ports, ",")), valueOrDash(row.ImageTag))
+  }
+}
+
+/**
+* Returns tag of image ('postgres:13' => '13', 'postgres' => 'latest')
+*/
+func imageTag(image string) string {
+  if image == "" { return "" }
+
+  if _, digest, isDigest := strings.Cut(image, "@"); isDigest {
+    return digest
+  }
+
+  /* ':' of registry port is followed by path */
+  if index := strings.LastIndex(image, ":"); index >= 0 && !strings.Contains(image[index:], "/") {
+    return image[index + 1:]
+  }
+
+  return "latest"
+}
+
+func formatDuration(duration time.Duration) string {
+  switch {
+  case duration < time.Minute:
+    return fmt.Sprintf("%ds", int(duration.Seconds()))
+  case duration < time.Hour:
+    return fmt.Sprintf("%dm", int(duration.Minutes()))
+  case duration < 24 * time.Hour:
+    return fmt.Sprintf("%dh%dm", int(duration.Hours()), int(duration.Minutes()) % 60)
+  }
+
+  return fmt.Sprintf("%dd%dh", int(duration.Hours()) / 24, int(duration.Hours()) % 24)
+}
+
+func valueOrDash(value string) string {
+  if value == "" {
+    return "-"
+  }
+  return value
+}
+
+func unique(values []string) (result []string) {
+  for i, value := range values {
+    if i == 0 || value != values[i - 1] {
+      result = append(result, value)
+    }
+  }
+  return
+}
@@ -0,0 +1,121 @@
+package statusCommand
+
+import (
+  "fmt"
+  "io"
+  "reflect"
+  "testing"
+  "time"
+  "devlab/lib/deploy"
+  "devlab/lib/docker"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/health"
+)
+
+/**
+* Docker client with fixed containers of projects
+*/
+type fakeClient struct {
+  containers map[string][]docker.Container
+}
+
+func (client *fakeClient) Containers(project string) ([]docker.Container, error) {
+  return client.containers[project], nil
+}
+
+func (client *fakeClient) Logs(container docker.Container, options docker.LogsOptions) (io.ReadCloser, error) {
+  return nil, fmt.Errorf("logs are not supported by fake client")
+}
+
+func TestStatus(t *testing.T) {
+  startedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+  tests := []struct {
+    name string
+    /* containers of project devlab-foo */
+    containers []docker.Container
+    /* services which pass health check probe */
+    healthy map[string]bool
+    expected []Row
+  }{
+    {
+      name: "no containers",
+      expected: []Row{
+        {Service: "postgres", Type: SERVICE_TYPE_SYSTEM, State: "missing", ImageTag: "13"},
+        {Service: "redis", Type: SERVICE_TYPE_SYSTEM, State: "missing", ImageTag: "latest"},
+        {Service: "users", Type: SERVICE_TYPE_APPLICATION, State: "missing", ImageTag: "develop"},
+      },
+    },
+    {
+      name: "running services with health checks and container health",
+      containers: []docker.Container{
+        {Service: "postgres", Image: "postgres:13", State: "running", StartedAt: startedAt, RestartCount: 2,
+          Ports: []docker.Port{{Ip: "0.0.0.0", PrivatePort: 5432, PublicPort: 20014, Type: "tcp"}, {Ip: "::", PrivatePort: 5432, PublicPort: 20014, Type: "tcp"}}},
+        {Service: "redis", Image: "redis:7", State: "running", StartedAt: startedAt, Health: "starting"},
+        {Service: "users", Image: "prefix/users:feature", State: "running", StartedAt: startedAt,
+          Ports: []docker.Port{{PrivatePort: 8080, Type: "tcp"}}},
+      },
+      healthy: map[string]bool{"postgres": true},
+      expected: []Row{
+        {Service: "postgres", Type: SERVICE_TYPE_SYSTEM, State: "running", StartedAt: &startedAt, Restarts: 2, Health: HEALTH_HEALTHY,
+          Ports: []string{"20014->5432/tcp"}, ImageTag: "13"},
+        {Service: "redis", Type: SERVICE_TYPE_SYSTEM, State: "running", StartedAt: &startedAt, Health: "starting", ImageTag: "7"},
+        {Service: "users", Type: SERVICE_TYPE_APPLICATION, State: "running", StartedAt: &startedAt, Health: HEALTH_UNHEALTHY, ImageTag: "feature"},
+      },
+    },
+    {
+      name: "stopped service is not probed",
+      containers: []docker.Container{
+        {Service: "postgres", Image: "postgres:13", State: "exited", StartedAt: startedAt, RestartCount: 5},
+      },
+      healthy: map[string]bool{"postgres": true},
+      expected: []Row{
+        {Service: "postgres", Type: SERVICE_TYPE_SYSTEM, State: "exited", Restarts: 5, ImageTag: "13"},
+        {Service: "redis", Type: SERVICE_TYPE_SYSTEM, State: "missing", ImageTag: "latest"},
+        {Service: "users", Type: SERVICE_TYPE_APPLICATION, State: "missing", ImageTag: "develop"},
+      },
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      client := &fakeClient{map[string][]docker.Container{"devlab-foo": test.containers, "devlab-bar": {{Service: "postgres", State: "running"}}}}
+
+      var probed []string
+      rows, err := Status(client, testTarget(t), func(serviceName string) error {
+        probed = append(probed, serviceName)
+        if test.healthy[serviceName] { return nil }
+        return fmt.Errorf("connection refused")
+      })
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      if !reflect.DeepEqual(rows, test.expected) {
+        t.Errorf("expected rows:\n%+v\ngot:\n%+v", test.expected, rows)
+      }
+      for _, serviceName := range probed {
+        if _, isChecked := testTarget(t).Checks[serviceName]; !isChecked {
+          t.Errorf("service '%s' without health check is probed", serviceName)
+        }
+      }
+    })
+  }
+}
+
+/**
+* Target of context 'foo' with system services postgres (tcp check), redis and application service users
+* (http check) which is not cloned
+*/
+func testTarget(t *testing.T) *deploy.Target {
+  return &deploy.Target{
+    ContextName: "foo",
+    ContextDir: t.TempDir(),
+    Variables: map[string]string{"COMPOSE_PROJECT_NAME": "devlab-foo"},
+    DockerComposeFiles: []*DockerComposeFileBuilder.DockerComposeFile{
+      {Services: map[string]*DockerComposeFileBuilder.Service{"redis": {Image: "redis"}, "postgres": {Image: "postgres:13"}}},
+      {Services: map[string]*DockerComposeFileBuilder.Service{"users": {Image: "prefix/users:develop"}}},
+    },
+    Checks: map[string]*health.Check{"postgres": {Tcp: 5432}, "users": {Http: "8080/health"}},
+  }
+}
@@ -0,0 +1,168 @@
+package toolCommand
+
+import (
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "devlab/lib/config"
+  "devlab/lib/deploy"
+  "devlab/lib/env"
+  "devlab/lib/errors"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/logger"
+  "devlab/lib/ports"
+  "devlab/lib/tools"
+  "devlab/lib/util"
+)
+
+const USAGE = "Usage: devlab tool [--context <context>] [--stop] [--no-browser] <tool> [args]"
+
+/**
+* devlab tool [--context <context>] [--stop] [--no-browser] <tool> [args]: runs utility as throwaway container
+* attached to network of context with connection settings of system services (context could be omitted
+* if there is only one context). Tools with web ui are run in background and opened in browser
+*/
+func Call(commandArgs []string) (err error) {
+  flags := flag.NewFlagSet("tool", flag.ExitOnError)
+  contextName := flags.String("context", "", "context which services tool connects to")
+  stop := flags.Bool("stop", false, "stop tool with web ui")
+  noBrowser := flags.Bool("no-browser", false, "print url of web ui instead of opening it")
+  /* arguments after name of tool are arguments of tool */
+  if err = flags.Parse(commandArgs); errors.CheckAndReturnIfError(err) { return }
+  if flags.NArg() == 0 {
+    logger.Text(USAGE)
+    for _, name := range tools.Names() {
+      logger.Text(fmt.Sprintf("  %-16s %s", name, tools.Tools[name].Description))
+    }
+    return
+  }
+  toolName, toolArgs := flags.Arg(0), flags.Args()[1:]
+
+  tool, err := tools.Find(toolName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  /* tools with web ui have no arguments, so flags could follow name of tool */
+  if tool.Port != 0 {
+    if err = flags.Parse(toolArgs); errors.CheckAndReturnIfError(err) { return }
+    toolArgs = flags.Args()
+  }
+
+  config, err := config.Load()
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if *contextName == "" {
+    *contextName, err = util.OnlyContext(config["contexts-path"], "devlab tool --context <context> ...")
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+
+  target, err := deploy.LoadTarget(config, *contextName)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if missing := tool.Missing(target.Variables); len(missing) > 0 {
+    err = fmt.Errorf("tool '%s' needs system services %s, they are not enabled in context '%s'", toolName, strings.Join(missing, ", "), *contextName)
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  containerName := target.Project() + "-tool-" + toolName
+  if *stop {
+    _, err = exec.Command("docker stop " + util.Quote(containerName) + " >/dev/null 2>&1")
+    if err != nil {
+      err = fmt.Errorf("tool '%s' is not running in context '%s'", toolName, *contextName)
+    }
+    if errors.CheckAndReturnIfError(err) { return }
+    logger.Info("Tool '%s' is stopped\n", toolName)
+    return
+  }
+
+  runArgs, err := tool.RunArgs(target.Variables, toolArgs)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if tool.Port == 0 {
+    /* tty is allocated only for terminal, so input of tool could be piped */
+    runFlags := "-i"
+    if exec.IsTerminal(os.Stdin) {
+      runFlags = "-it"
+    }
+    err = exec.Interactive("docker run " + runFlags + " " + util.QuoteAll(runArgs))
+    errors.CheckAndReturnIfError(err)
+    return
+  }
+
+  hostPort, err := runUi(tool, containerName, runArgs, config["contexts-path"], config["ports-range"])
+  if errors.CheckAndReturnIfError(err) { return }
+
+  url := fmt.Sprintf("http://localhost:%d/", hostPort)
+  err = tools.WaitUi(url, tools.UI_TIMEOUT)
+  if errors.CheckAndReturnIfError(err) { return }
+  logger.Info("Tool '%s' is running on %s, stop it with 'devlab tool --context %s --stop %s'\n", toolName, url, *contextName, toolName)
+
+  if *noBrowser {
+    fmt.Println(url)
+    return
+  }
+
+  /* login page with credentials is written to build folder of context, it is ignored by git like .env */
+  page, err := tool.LoginPage(url, target.Variables)
+  if errors.CheckAndReturnIfError(err) { return }
+  if page != "" {
+    path, err := filepath.Abs(filepath.Join(target.ContextDir, env.BUILD_DIR, toolName + "-login.html"))
+    if errors.CheckAndReturnIfError(err) { return err }
+    if err = files.CreateDir(filepath.Dir(path)); errors.CheckAndReturnIfError(err) { return err }
+    if err = os.WriteFile(path, []byte(page), 0600); errors.CheckAndReturnIfError(err) { return err }
+    url = "file://" + path
+  }
+
+  if err = exec.Browser(url); err != nil {
+    logger.Warn("couldn't open browser, open the url manually\n")
+  }
+
+  return
+}
+
+/**
+* Runs tool with web ui in background on free host port (running tool is reused), returns host port
+*/
+func runUi(tool *tools.Tool, containerName string, runArgs []string, contextsPath string, portsRange string) (int, error) {
+  containerPort := strconv.Itoa(tool.Port)
+  if out, err := exec.Command("docker port " + util.Quote(containerName) + " " + containerPort + " 2>/dev/null"); err == nil {
+    _, hostPort, _ := strings.Cut(strings.Split(strings.TrimSpace(out), "\n")[0], ":")
+    if port, err := strconv.Atoi(hostPort); err == nil {
+      return port, nil
+    }
+  }
+
+  hostPort, err := freePort(contextsPath, portsRange)
+  if err != nil { return 0, err }
+
+  runArgs = append([]string{"-d", "--name", containerName, "-p", strconv.Itoa(hostPort) + ":" + containerPort}, runArgs...)
+  out, err := exec.Command("docker run " + util.QuoteAll(runArgs) + " 2>&1")
+  if err != nil {
+    return 0, fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
+  }
+
+  return hostPort, nil
+}
+
+/**
+* Returns free port of ports range which is not allocated by contexts
+*/
+func freePort(contextsPath string, value string) (int, error) {
+  portsRange, err := ports.ParseRange(value)
+  if err != nil { return 0, err }
+
+  usedPorts, err := ports.UsedByOtherContexts(contextsPath, "")
+  if err != nil { return 0, err }
+
+  for port := portsRange.From; port <= portsRange.To; port++ {
+    if _, isUsed := usedPorts[port]; !isUsed && ports.IsFree(port) {
+      return port, nil
+    }
+  }
+
+  return 0, fmt.Errorf("there are no free ports in range %s", value)
+}
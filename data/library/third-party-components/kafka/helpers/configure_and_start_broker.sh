@@ -1,7 +1,9 @@
 cd $KAFKA_HOME
 
-export BROKER_ID=$(hostname -i | sed 's/\([0-9]*\.\)*\([0-9]*\)/\2/')
+# broker id and log folder are fixed, so data kept in kafka_data volume is reused by new containers
+export BROKER_ID=${BROKER_ID:-1}
 sed -i 's/^\(broker\.id=\).*/\1'$BROKER_ID'/' config/server.properties
+sed -i 's|^\(log\.dirs=\).*|\1/kafka/kafka-logs|' config/server.properties
 
 sed -i 's|^#\(listeners=PLAINTEXT://\)\(:9092\)|\1'`hostname -i`'\2|' config/server.properties
 
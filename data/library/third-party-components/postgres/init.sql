@@ -1 +0,0 @@
-CREATE DATABASE users;
\ No newline at end of file
@@ -2,22 +2,97 @@ package main
 
 import (
   "os"
+  "devlab/bin/config"
+  "devlab/bin/consul"
   "devlab/bin/context"
   "devlab/bin/create-docker-compose"
+  "devlab/bin/db"
+  "devlab/bin/deploy"
+  "devlab/bin/env"
+  "devlab/bin/kafka"
+  "devlab/bin/keycloak"
+  "devlab/bin/kibana"
+  "devlab/bin/library"
+  "devlab/bin/logs"
+  "devlab/bin/ports"
+  "devlab/bin/secret"
+  "devlab/bin/setup"
+  "devlab/bin/snapshot"
+  "devlab/bin/status"
+  "devlab/bin/tool"
+  "devlab/lib/config"
 )
 
 func main() {     
-  switch os.Args[1] {
+  args := config.ParseFlags(os.Args[1:])
+
+  switch args[0] {
   case "context":
-    switch os.Args[2] {
+    switch args[1] {
     case "create":
-      Context.Create(os.Args[3])         
+      Context.Create(args[2])         
     case "set":
-      Context.Set(os.Args[3])   
+      Context.Set(args[2])   
+    case "show":
+      Context.Show(args[2])
     }
     break 
+  case "setup":
+    setup.Call(args[1:])
+    break
+  case "config":
+    configCommand.Call(args[1:])
+    break
   case "create-docker-compose":
-    createDockerCompose.Call(os.Args[2])
+    createDockerCompose.Call(args[1:])
+    break
+  case "ports":
+    portsCommand.Call(args[1:])
+    break
+  case "env":
+    envCommand.Call(args[1:])
+    break
+  case "secret":
+    secretCommand.Call(args[1:])
+    break
+  case "up":
+    deployCommand.Up(args[1:])
+    break
+  case "down":
+    deployCommand.Down(args[1:])
+    break
+  case "proxy":
+    deployCommand.Proxy(args[1:])
+    break
+  case "status":
+    statusCommand.Call(args[1:])
+    break
+  case "logs":
+    logsCommand.Call(args[1:])
+    break
+  case "library":
+    libraryCommand.Call(args[1:])
+    break
+  case "kibana":
+    kibanaCommand.Call(args[1:])
+    break
+  case "kafka":
+    kafkaCommand.Call(args[1:])
+    break
+  case "db":
+    dbCommand.Call(args[1:])
+    break
+  case "consul":
+    consulCommand.Call(args[1:])
+    break
+  case "keycloak":
+    keycloakCommand.Call(args[1:])
+    break
+  case "snapshot":
+    snapshotCommand.Call(args[1:])
+    break
+  case "tool":
+    toolCommand.Call(args[1:])
     break
   }
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+package args
+
+import (
+  "flag"
+)
+
+/**
+* Parses command line arguments with flags placed anywhere (before, between or after positional arguments),
+* returns positional arguments
+*/
+func Parse(flags *flag.FlagSet, args []string) (params []string, err error) {
+  for {
+    if err = flags.Parse(args); err != nil { return }
+
+    args = flags.Args()
+    if len(args) == 0 { return }
+
+    params = append(params, args[0])
+    args = args[1:]
+  }
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "devlab/lib/errors"
+  "devlab/lib/files"
+  "devlab/lib/util"
+  "devlab/lib/yml"
+)
+
+const PROJECT_CONFIG_FILE = files.MAIN_CONFIG_FILE
+const PROJECT_CONFIG_EXAMPLE_FILE = ".config.example"
+const ENV_PREFIX = "DEVLAB_"
+
+/* Layers in order of priority (every next layer overrides previous one) */
+const (
+  ORIGIN_DEFAULT = "default"
+  ORIGIN_GLOBAL = "global"
+  ORIGIN_PROJECT = "project"
+  ORIGIN_ENV = "env"
+  ORIGIN_FLAG = "flag"
+)
+
+/* Built-in defaults of .config keys */
+var Defaults = map[string]string{
+  "data-path": "data",
+  "contexts-path": "contexts",
+  "library-path": "data/library",
+  "library-sources": "",
+  "images-prefix": "",
+  "docker-registry-host": "",
+  "docker-images-push-prefix": "",
+  "docker-compose-version": "2.0",
+  "github-repository-path": "",
+  "base-branch": "develop",
+  "ports-range": "20000-29999",
+  "deploy-strategy": "docker-compose",
+  "kube-context": "minikube",
+  "kube-namespace": "",
+}
+
+type Value struct {
+  Value string
+  Origin string
+  Source string
+}
+
+/* Keys with paths, relative paths are relative to project root */
+var PATH_KEYS = []string{"data-path", "contexts-path", "library-path"}
+
+/* Values passed as command line flags (--<key>=<value>) */
+var flagValues = make(map[string]string)
+
+/**
+* Returns config values merged from all layers, paths are absolute, so devlab works from any folder of project
+*/
+func Load() (config map[string]string, err error) {
+  values, err := LoadWithOrigins()
+  if err != nil { return make(map[string]string), err }
+
+  projectConfigPath, err := files.FindMainConfig(".")
+  if err != nil { return make(map[string]string), err }
+  root := filepath.Dir(projectConfigPath)
+
+  config = make(map[string]string)
+  for key, value := range values {
+    config[key] = value.Value
+  }
+
+  for _, key := range PATH_KEYS {
+    config[key] = resolvePath(values[key], values[key].Value, root)
+  }
+
+  /* local folders and tarballs of library sources are paths too */
+  var sources []string
+  for _, location := range strings.Split(values["library-sources"].Value, ",") {
+    location = strings.TrimSpace(location)
+    if location != "" && !strings.HasPrefix(location, "git+") && !strings.Contains(location, "://") {
+      location = resolvePath(values["library-sources"], location, root)
+    }
+    sources = append(sources, location)
+  }
+  config["library-sources"] = strings.Join(sources, ",")
+
+  return
+}
+
+/**
+* Returns config values merged from all layers with the layer (and file or variable) every value came from:
+* defaults, then ~/.config/devlab/config.yml, then project .config, then DEVLAB_* variables, then flags
+*/
+func LoadWithOrigins() (values map[string]Value, err error) {
+  values = make(map[string]Value)
+
+  for key, value := range Defaults {
+    values[key] = Value{value, ORIGIN_DEFAULT, ""}
+  }
+
+  globalConfigPath := GlobalConfigPath()
+  if err = mergeFile(values, globalConfigPath, ORIGIN_GLOBAL); err != nil { return }
+
+  /* project .config is required, but other layers are still loaded without it (e.g. for setup) */
+  projectConfig, projectConfigPath, projectErr := files.ReadMainConfigFrom(".")
+  for key, value := range projectConfig {
+    values[key] = Value{value, ORIGIN_PROJECT, projectConfigPath}
+  }
+
+  for key := range values {
+    if value, ok := os.LookupEnv(EnvName(key)); ok {
+      values[key] = Value{value, ORIGIN_ENV, EnvName(key)}
+    }
+  }
+
+  for key, value := range flagValues {
+    values[key] = Value{value, ORIGIN_FLAG, "--" + key}
+  }
+
+  return values, projectErr
+}
+
+/**
+* Returns sorted list of known config keys
+*/
+func Keys(values map[string]Value) (keys []string) {
+  for key := range values {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+
+  return
+}
+
+/**
+* Extracts '--<key>=<value>' and '--<key> <value>' flags for known config keys from command line arguments,
+* returns the rest arguments
+*/
+func ParseFlags(args []string) (rest []string) {
+  for i := 0; i < len(args); i++ {
+    arg := args[i]
+    if !strings.HasPrefix(arg, "--") {
+      rest = append(rest, arg)
+      continue
+    }
+
+    key, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+    if _, isKnown := Defaults[key]; !isKnown {
+      rest = append(rest, arg)
+      continue
+    }
+
+    if !hasValue && i + 1 < len(args) {
+      i++
+      value = args[i]
+    }
+    flagValues[key] = value
+  }
+
+  return
+}
+
+/**
+* Returns name of environment variable overriding config key ('contexts-path' => 'DEVLAB_CONTEXTS_PATH')
+*/
+func EnvName(key string) string {
+  return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+/**
+* Returns path to global (per user) config file
+*/
+func GlobalConfigPath() string {
+  configHome := os.Getenv("XDG_CONFIG_HOME")
+  if configHome == "" {
+    home, _ := os.UserHomeDir()
+    configHome = filepath.Join(home, ".config")
+  }
+
+  return filepath.Join(configHome, "devlab", "config.yml")
+}
+
+/**
+* Returns path to project .config (it may not exist yet)
+*/
+func ProjectConfigPath() (string, error) {
+  path, err := files.FindMainConfig(".")
+  if _, isNotFound := err.(*files.MainConfigNotFoundError); isNotFound {
+    root, err := FindProjectRoot()
+    if err != nil { return "", err }
+
+    return filepath.Join(root, PROJECT_CONFIG_FILE), nil
+  }
+
+  return path, err
+}
+
+/**
+* Finds devlab project root walking up from current working directory: the root is the first folder
+* containing .config or .config.example
+*/
+func FindProjectRoot() (string, error) {
+  path, searchedPaths, err := files.FindUp(".", PROJECT_CONFIG_FILE, PROJECT_CONFIG_EXAMPLE_FILE)
+  if err != nil { return "", err }
+  if path == "" {
+    return "", &files.MainConfigNotFoundError{SearchedPaths: searchedPaths}
+  }
+
+  return filepath.Dir(path), nil
+}
+
+/**
+* Sets config value in project .config (or in global config if isGlobal) keeping order of other keys
+*/
+func Set(key string, value string, isGlobal bool) (err error) {
+  if _, isKnown := Defaults[key]; !isKnown {
+    return fmt.Errorf("unknown config key '%s', keys: %s", key, strings.Join(util.SortedKeys(Defaults), ", "))
+  }
+
+  path := GlobalConfigPath()
+  if !isGlobal {
+    path, err = ProjectConfigPath()
+    if errors.CheckAndReturnIfError(err) { return }
+  }
+
+  isExists, _ := files.IsExists(path)
+  if !isExists {
+    if err = files.CreateDir(filepath.Dir(path)); err != nil { return }
+    if err = files.WriteFileAtomic(path, ""); err != nil { return }
+  }
+
+  /* value is quoted if needed, comments and order of keys are kept */
+  return files.UpdateYaml(path, []string{key}, value)
+}
+
+/**
+* Merges values of one level yaml file (if it exists) into values
+*/
+func mergeFile(values map[string]Value, path string, origin string) (err error) {
+  isExists, _ := files.IsExists(path)
+  if !isExists { return }
+
+  data, err := files.ReadTextFile(path)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  fileValues, err := yml.ParseOneLevelYAML(data)
+  if err != nil { return fmt.Errorf("%s: %s", path, err) }
+
+  for key, value := range fileValues {
+    values[key] = Value{value, origin, path}
+  }
+
+  return
+}
+
+/**
+* Returns absolute path of config value: paths typed in environment variables and flags are relative to current
+* folder, paths of config files are relative to project root
+*/
+func resolvePath(value Value, path string, root string) string {
+  if path == "" || filepath.IsAbs(path) { return path }
+
+  if value.Origin == ORIGIN_ENV || value.Origin == ORIGIN_FLAG {
+    if absolutePath, err := filepath.Abs(path); err == nil { return absolutePath }
+    return path
+  }
+
+  return filepath.Join(root, path)
+}
@@ -0,0 +1,300 @@
+package consul
+
+import (
+  "bytes"
+  "encoding/base64"
+  "encoding/json"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "devlab/lib/env"
+  "devlab/lib/files"
+  "devlab/lib/secrets"
+  yamlv3 "gopkg.in/yaml.v3"
+  "devlab/lib/util"
+)
+
+/* Section of settings.yml with keys of context: <prefix>: {<key>: <value>} is key <prefix>/<key> */
+const KV_SECTION = "consul-kv"
+
+/* Settings of context section: folder of yaml files of keys (relative to context folder) and registration
+* of application services in consul catalog */
+const SETTINGS_GROUP = "consul"
+const KV_DIR_KEY = "kv"
+const REGISTER_SERVICES_KEY = "register-services"
+
+/* Service of consul, keys and services are managed with consul cli run inside its container */
+const SERVICE = "consul"
+const KV_EXPORT_COMMAND = "consul kv export"
+const KV_IMPORT_COMMAND = "consul kv import -"
+/* registers service with definition read from stdin */
+const REGISTER_COMMAND = "file=/tmp/devlab-service-$$.json && cat > $file && consul services register $file; status=$?; rm -f $file; exit $status"
+
+/* Tag of services registered by devlab */
+const SERVICE_TAG = "devlab"
+
+const ACTION_NONE = "ok"
+const ACTION_CREATE = "create"
+const ACTION_UPDATE = "update"
+/* key exists in consul, but is not declared */
+const ACTION_UNDECLARED = "undeclared"
+
+/**
+* Difference between declared and actual value of key
+*/
+type Change struct {
+  Key string
+  Action string
+  Value string
+  Current string
+}
+
+/**
+* Entry of 'consul kv export' and 'consul kv import' (value is base64 encoded)
+*/
+type entry struct {
+  Key string `json:"key"`
+  Flags int `json:"flags"`
+  Value string `json:"value"`
+}
+
+/**
+* Service registered in consul catalog
+*/
+type Registration struct {
+  Id string `json:"ID"`
+  Name string `json:"Name"`
+  Address string `json:"Address"`
+  Port int `json:"Port,omitempty"`
+  Tags []string `json:"Tags"`
+}
+
+/**
+* Returns keys declared for context: keys of yaml files of kv folder (nested keys of file <path>.yml are prefixed
+* with <path>) overridden by keys of consul-kv section of settings.yml. Secret references are resolved,
+* ${VAR} are replaced with variables of context
+*/
+func Declared(context map[string]map[string]map[string]string, contextDir string, variables map[string]string) (keys map[string]string, err error) {
+  keys = make(map[string]string)
+
+  if kvDir := context["context"][SETTINGS_GROUP][KV_DIR_KEY]; kvDir != "" {
+    if err = readDir(filepath.Join(contextDir, kvDir), keys); err != nil { return nil, err }
+  }
+
+  for prefix, values := range context[KV_SECTION] {
+    for key, value := range values {
+      keys[Join(prefix, key)] = value
+    }
+  }
+
+  /* secrets are resolved after expansion, so '${...}' in value of secret is kept as is */
+  for key, value := range keys {
+    if keys[key], err = env.Expand(value, variables); err != nil {
+      return nil, fmt.Errorf("consul key '%s': %s", key, err)
+    }
+    if keys[key], err = secrets.Resolve(keys[key]); err != nil {
+      return nil, fmt.Errorf("consul key '%s': %s", key, err)
+    }
+  }
+
+  return
+}
+
+/**
+* Parses output of 'consul kv export': key => value (folders are skipped)
+*/
+func ParseExport(out string) (keys map[string]string, err error) {
+  var entries []entry
+  if err = json.Unmarshal([]byte(out), &entries); err != nil {
+    return nil, fmt.Errorf("couldn't parse keys of consul: %s", err)
+  }
+
+  keys = make(map[string]string)
+  for _, item := range entries {
+    if strings.HasSuffix(item.Key, "/") { continue }
+
+    value, err := base64.StdEncoding.DecodeString(item.Value)
+    if err != nil {
+      return nil, fmt.Errorf("consul key '%s': %s", item.Key, err)
+    }
+    keys[item.Key] = string(value)
+  }
+
+  return
+}
+
+/**
+* Returns changes of keys sorted by key: declared keys which are missing or differ from actual ones
+* and actual keys which are not declared
+*/
+func Diff(declared map[string]string, actual map[string]string) (changes []*Change) {
+  keys := make(map[string]string)
+  for key := range declared {
+    keys[key] = key
+  }
+  for key := range actual {
+    keys[key] = key
+  }
+
+  for _, key := range util.SortedKeys(keys) {
+    value, isDeclared := declared[key]
+    current, isActual := actual[key]
+    change := &Change{Key: key, Action: ACTION_NONE, Value: value, Current: current}
+
+    switch {
+    case !isDeclared:
+      change.Action = ACTION_UNDECLARED
+    case !isActual:
+      change.Action = ACTION_CREATE
+    case value != current:
+      change.Action = ACTION_UPDATE
+    }
+
+    changes = append(changes, change)
+  }
+
+  return
+}
+
+/**
+* Returns input of 'consul kv import' with created and updated keys of changes ("" if there is nothing to import)
+*/
+func ImportData(changes []*Change) (string, error) {
+  var entries []entry
+  for _, change := range changes {
+    if change.Action != ACTION_CREATE && change.Action != ACTION_UPDATE { continue }
+    entries = append(entries, entry{Key: change.Key, Value: base64.StdEncoding.EncodeToString([]byte(change.Value))})
+  }
+  if len(entries) == 0 { return "", nil }
+
+  data, err := json.Marshal(entries)
+  return string(data), err
+}
+
+/**
+* Returns keys as nested yaml (format of files of kv folder), keys of prefix are exported relative to it
+*/
+func Export(keys map[string]string, prefix string) (string, error) {
+  prefix = strings.Trim(prefix, "/")
+  tree := make(map[string]interface{})
+
+  for _, key := range util.SortedKeys(keys) {
+    relativeKey := key
+    if prefix != "" {
+      if !strings.HasPrefix(key, prefix + "/") { continue }
+      relativeKey = strings.TrimPrefix(key, prefix + "/")
+    }
+
+    parts := strings.Split(relativeKey, "/")
+    node := tree
+    for i, part := range parts {
+      if i == len(parts) - 1 {
+        if _, isFolder := node[part].(map[string]interface{}); isFolder {
+          return "", fmt.Errorf("consul key '%s' is both value and folder, it couldn't be exported to yaml", key)
+        }
+        node[part] = keys[key]
+        break
+      }
+
+      child, ok := node[part].(map[string]interface{})
+      if !ok {
+        if _, isValue := node[part]; isValue {
+          return "", fmt.Errorf("consul key '%s' is both value and folder, it couldn't be exported to yaml", strings.Join(parts[:i + 1], "/"))
+        }
+        child = make(map[string]interface{})
+        node[part] = child
+      }
+      node = child
+    }
+  }
+
+  var data bytes.Buffer
+  encoder := yamlv3.NewEncoder(&data)
+  encoder.SetIndent(2)
+  if err := encoder.Encode(tree); err != nil { return "", err }
+
+  return data.String(), encoder.Close()
+}
+
+/**
+* Returns registrations of application services of context (address is name of service in docker network,
+* port is <SERVICE>_PORT variable)
+*/
+func Registrations(serviceNames []string, contextName string, variables map[string]string) (registrations []*Registration) {
+  for _, serviceName := range serviceNames {
+    registration := &Registration{Id: serviceName, Name: serviceName, Address: serviceName, Tags: []string{SERVICE_TAG, contextName}}
+    registration.Port, _ = strconv.Atoi(variables[env.Name(serviceName) + "_PORT"])
+    registrations = append(registrations, registration)
+  }
+
+  return
+}
+
+/**
+* Returns definition of service of 'consul services register'
+*/
+func Definition(registration *Registration) (string, error) {
+  data, err := json.Marshal(map[string]*Registration{"Service": registration})
+  return string(data), err
+}
+
+/**
+* Returns key of prefix
+*/
+func Join(prefix string, key string) string {
+  prefix, key = strings.Trim(prefix, "/"), strings.Trim(key, "/")
+  if prefix == "" { return key }
+  return prefix + "/" + key
+}
+
+/**
+* Reads keys of yaml files of folder
+*/
+func readDir(dir string, keys map[string]string) error {
+  if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+    return fmt.Errorf("consul kv folder '%s' is not found", dir)
+  }
+
+  return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+    if err != nil { return err }
+    extension := filepath.Ext(path)
+    if info.IsDir() || (extension != ".yml" && extension != ".yaml") { return nil }
+
+    relativePath, err := filepath.Rel(dir, strings.TrimSuffix(path, extension))
+    if err != nil { return err }
+
+    data, err := files.ReadTextFile(path)
+    if err != nil { return err }
+
+    var document yamlv3.Node
+    if err = yamlv3.Unmarshal([]byte(data), &document); err != nil {
+      return fmt.Errorf("%s: %s", path, err)
+    }
+    if len(document.Content) == 0 { return nil }
+
+    if err = flatten(filepath.ToSlash(relativePath), document.Content[0], keys); err != nil {
+      return fmt.Errorf("%s: %s", path, err)
+    }
+    return nil
+  })
+}
+
+/**
+* Adds scalars of yaml node as keys of prefix (nested mappings are nested folders)
+*/
+func flatten(prefix string, node *yamlv3.Node, keys map[string]string) error {
+  switch node.Kind {
+  case yamlv3.ScalarNode:
+    keys[prefix] = node.Value
+    return nil
+  case yamlv3.MappingNode:
+    for i := 0; i + 1 < len(node.Content); i += 2 {
+      if err := flatten(Join(prefix, node.Content[i].Value), node.Content[i + 1], keys); err != nil { return err }
+    }
+    return nil
+  }
+
+  return fmt.Errorf("line %d: value of key '%s' should be string or mapping", node.Line, prefix)
+}
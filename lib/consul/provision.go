@@ -0,0 +1,87 @@
+package consul
+
+import (
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+  "time"
+  "devlab/lib/deploy"
+)
+
+/* How long to wait for consul before keys are loaded */
+const CONSUL_TIMEOUT = 2 * time.Minute
+
+/**
+* Loads keys declared for context into consul once it is healthy and registers application services
+* if it is enabled in settings.yml (used by 'devlab up')
+*/
+func Provision(backend deploy.Backend, target *deploy.Target) error {
+  settings := target.Context["context"][SETTINGS_GROUP]
+  isRegister := settings[REGISTER_SERVICES_KEY] == "true"
+  if len(target.Context[KV_SECTION]) == 0 && settings[KV_DIR_KEY] == "" && !isRegister { return nil }
+
+  if err := backend.Wait(CONSUL_TIMEOUT, SERVICE); err != nil { return err }
+
+  changes, err := Changes(backend, target)
+  if err != nil { return err }
+  if err = Apply(backend, changes); err != nil { return err }
+
+  if !isRegister { return nil }
+
+  /* generated files are system and application ones */
+  var serviceNames []string
+  for serviceName := range target.DockerComposeFiles[len(target.DockerComposeFiles) - 1].Services {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+  for _, registration := range Registrations(serviceNames, target.ContextName, target.Variables) {
+    definition, err := Definition(registration)
+    if err != nil { return err }
+
+    if err = backend.Stream(SERVICE, REGISTER_COMMAND, strings.NewReader(definition), io.Discard); err != nil {
+      return fmt.Errorf("consul service '%s': %s", registration.Name, err)
+    }
+  }
+
+  return nil
+}
+
+/**
+* Returns differences between declared keys and keys of consul
+*/
+func Changes(backend deploy.Backend, target *deploy.Target) ([]*Change, error) {
+  declared, err := Declared(target.Context, target.ContextDir, target.Variables)
+  if err != nil { return nil, err }
+
+  actual, err := Keys(backend)
+  if err != nil { return nil, err }
+
+  return Diff(declared, actual), nil
+}
+
+/**
+* Puts created and updated keys of changes into consul (undeclared keys are kept)
+*/
+func Apply(backend deploy.Backend, changes []*Change) error {
+  data, err := ImportData(changes)
+  if err != nil || data == "" { return err }
+
+  return backend.Stream(SERVICE, KV_IMPORT_COMMAND, strings.NewReader(data), io.Discard)
+}
+
+/**
+* Returns keys of consul (only stdout of export is parsed, warnings of consul are printed to the terminal)
+*/
+func Keys(backend deploy.Backend) (map[string]string, error) {
+  var out strings.Builder
+  if err := backend.Stream(SERVICE, KV_EXPORT_COMMAND, nil, &out); err != nil {
+    return nil, fmt.Errorf("couldn't read keys of consul (is service '%s' running?): %s", SERVICE, err)
+  }
+  /* output is empty in dry run */
+  if strings.TrimSpace(out.String()) == "" {
+    return map[string]string{}, nil
+  }
+
+  return ParseExport(out.String())
+}
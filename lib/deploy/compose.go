@@ -0,0 +1,159 @@
+package deploy
+
+import (
+  "fmt"
+  "io"
+  "net"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/exec"
+  "devlab/lib/health"
+  "devlab/lib/logger"
+  "devlab/lib/util"
+)
+
+/**
+* Docker compose strategy: system and application compose files of context are run as one compose project
+*/
+type Compose struct {
+  target *Target
+  /* 'docker compose' or 'docker-compose' */
+  dockerCompose string
+}
+
+func (backend *Compose) Up() (err error) {
+  network := backend.target.Variables["DOCKER_NETWORK"]
+  err = backend.target.run(fmt.Sprintf("docker network inspect %s >/dev/null 2>&1 || docker network create %s", util.Quote(network), util.Quote(network)))
+  if err != nil { return }
+
+  return backend.target.run(backend.command() + " up -d --remove-orphans")
+}
+
+func (backend *Compose) Down() error {
+  return backend.target.run(backend.command() + " down --remove-orphans")
+}
+
+/**
+* Ports of docker compose services are published on host by docker itself
+*/
+func (backend *Compose) Proxy() error {
+  logger.Info("Ports of services are published by docker compose, see 'devlab ports %s'\n", backend.target.ContextName)
+  return nil
+}
+
+/**
+* Checks are run from host on published ports (tcp, http) and inside containers (command)
+*/
+func (backend *Compose) Wait(timeout time.Duration, serviceNames ...string) error {
+  return backend.target.wait(timeout, serviceNames, backend.probe, func(serviceName string) string {
+    out, _ := exec.Command(fmt.Sprintf("%s logs --no-color --tail %d %s 2>&1", backend.command(), LOGS_TAIL, util.Quote(serviceName)))
+    return out
+  })
+}
+
+func (backend *Compose) Probe(serviceName string) error {
+  check, err := backend.target.Check(serviceName)
+  if err != nil { return err }
+
+  return backend.probe(serviceName, check, COMMAND_PROBE_TIMEOUT)
+}
+
+func (backend *Compose) Exec(serviceName string, command string) (string, error) {
+  return backend.target.output(fmt.Sprintf("%s exec -T %s sh -c %s", backend.command(), util.Quote(serviceName), util.Quote(command)))
+}
+
+func (backend *Compose) Stream(serviceName string, command string, stdin io.Reader, stdout io.Writer) error {
+  return backend.target.stream(fmt.Sprintf("%s exec -T %s sh -c %s", backend.command(), util.Quote(serviceName), util.Quote(command)), stdin, stdout)
+}
+
+/**
+* Returns names of running services of context (errors of docker compose are printed to the terminal)
+*/
+func (backend *Compose) Running() ([]string, error) {
+  var out strings.Builder
+  if err := exec.Stream(backend.command() + " ps --services --filter status=running", nil, &out); err != nil { return nil, err }
+
+  return strings.Fields(out.String()), nil
+}
+
+/**
+* Stops services keeping their containers
+*/
+func (backend *Compose) Stop(serviceNames ...string) error {
+  if len(serviceNames) == 0 { return nil }
+  return backend.target.run(backend.command() + " stop " + util.QuoteAll(serviceNames))
+}
+
+/**
+* Starts stopped services
+*/
+func (backend *Compose) Start(serviceNames ...string) error {
+  if len(serviceNames) == 0 { return nil }
+  return backend.target.run(backend.command() + " start " + util.QuoteAll(serviceNames))
+}
+
+func (backend *Compose) probe(serviceName string, check *health.Check, commandTimeout time.Duration) error {
+  switch {
+  case check.Tcp != 0:
+    hostPort, err := backend.hostPort(serviceName, check.Tcp)
+    if err != nil { return err }
+
+    connection, err := net.DialTimeout("tcp", "localhost:" + strconv.Itoa(hostPort), PROBE_TIMEOUT)
+    if err != nil { return err }
+    return connection.Close()
+
+  case check.Http != "":
+    port, path, err := check.HttpPortAndPath()
+    if err != nil { return err }
+    hostPort, err := backend.hostPort(serviceName, port)
+    if err != nil { return err }
+
+    client := http.Client{Timeout: PROBE_TIMEOUT}
+    response, err := client.Get(fmt.Sprintf("http://localhost:%d%s", hostPort, path))
+    if err != nil { return err }
+    response.Body.Close()
+    if response.StatusCode >= 400 {
+      return fmt.Errorf("%s responded with status %d", path, response.StatusCode)
+    }
+    return nil
+  }
+
+  out, err := exec.CommandWithTimeout(fmt.Sprintf("%s exec -T %s sh -c %s 2>&1", backend.command(), util.Quote(serviceName), util.Quote(check.Command)), commandTimeout)
+  if err != nil {
+    return fmt.Errorf("%s %s", err, out)
+  }
+  return nil
+}
+
+/**
+* Returns host port published for container port of service
+*/
+func (backend *Compose) hostPort(serviceName string, containerPort int) (int, error) {
+  for _, key := range []string{strconv.Itoa(containerPort), strconv.Itoa(containerPort) + "/tcp"} {
+    if hostPort, ok := backend.target.State.Ports[serviceName][key]; ok {
+      return hostPort, nil
+    }
+  }
+
+  return 0, fmt.Errorf("port %d is not published", containerPort)
+}
+
+/**
+* Returns docker compose command for project of context ('docker compose' or 'docker-compose' if plugin
+* is not installed), relative paths in compose files are relative to context folder
+*/
+func (backend *Compose) command() string {
+  if backend.dockerCompose == "" {
+    backend.dockerCompose = "docker compose"
+    if _, err := exec.Command("docker compose version 2>/dev/null"); err != nil {
+      backend.dockerCompose = "docker-compose"
+    }
+  }
+
+  contextDir := backend.target.ContextDir
+  return fmt.Sprintf("%s -p %s --project-directory %s -f %s -f %s", backend.dockerCompose, util.Quote(backend.target.Project()), util.Quote(contextDir),
+    util.Quote(contextDir + "/" + DockerComposeFileBuilder.SYSTEM_COMPOSE), util.Quote(contextDir + "/" + DockerComposeFileBuilder.APPLICATION_COMPOSE))
+}
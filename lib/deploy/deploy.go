@@ -0,0 +1,233 @@
+package deploy
+
+import (
+  "fmt"
+  "io"
+  "path/filepath"
+  "sort"
+  "strings"
+  "time"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/env"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/health"
+  "devlab/lib/library"
+  "devlab/lib/logger"
+  "devlab/lib/state"
+)
+
+const STRATEGY_DOCKER_COMPOSE = "docker-compose"
+const STRATEGY_KUBERNETES = "kubernetes"
+
+var Strategies = []string{STRATEGY_DOCKER_COMPOSE, STRATEGY_KUBERNETES}
+
+/* Number of log lines printed for service which is not healthy */
+const LOGS_TAIL = 20
+
+/* Timeout of single probe of health check */
+const PROBE_TIMEOUT = 2 * time.Second
+
+/* Timeout of health check command run inside container (it is bounded by time left to wait) */
+const COMMAND_PROBE_TIMEOUT = 10 * time.Second
+
+/**
+* Deploy strategy: the way services of context are run
+*/
+type Backend interface {
+  /* creates or updates services of context */
+  Up() error
+  /* stops and removes services of context */
+  Down() error
+  /* makes services of context reachable on host ports allocated for context */
+  Proxy() error
+  /* waits until services with health checks (all or set ones) are healthy, returns error listing services which are not */
+  Wait(timeout time.Duration, serviceNames ...string) error
+  /* probes health check of service once, returns nil if service is healthy */
+  Probe(serviceName string) error
+  /* runs shell command inside container of service, returns its output */
+  Exec(serviceName string, command string) (string, error)
+  /* runs shell command inside container of service with stdin and stdout connected to reader and writer */
+  Stream(serviceName string, command string, stdin io.Reader, stdout io.Writer) error
+}
+
+/**
+* Context to deploy: its settings, state and generated docker compose files (system, application)
+*/
+type Target struct {
+  ContextName string
+  ContextDir string
+  Config map[string]string
+  Context map[string]map[string]map[string]string
+  State *state.State
+  DockerComposeFiles []*DockerComposeFileBuilder.DockerComposeFile
+  /* variables of context .env, secrets are resolved */
+  Variables map[string]string
+  Checks map[string]*health.Check
+  /* commands are printed instead of being executed */
+  DryRun bool
+}
+
+/**
+* Loads target of context, docker compose files should be generated before
+*/
+func LoadTarget(config map[string]string, contextName string) (target *Target, err error) {
+  target = &Target{ContextName: contextName, Config: config}
+  target.ContextDir = filepath.Join(config["contexts-path"], contextName)
+
+  if target.Context, err = files.ReadContextConfig(target.ContextDir + "/settings.yml"); err != nil { return }
+  if target.State, err = state.Load(target.ContextDir); err != nil { return }
+  if target.DockerComposeFiles, err = DockerComposeFileBuilder.ReadGenerated(target.ContextDir, contextName); err != nil { return }
+
+  componentsLibrary, err := library.ForContext(config, target.ContextDir)
+  if err != nil { return }
+  if target.Checks, err = health.Load(componentsLibrary.Healthchecks(), target.Context); err != nil { return }
+
+  parameters, err := componentsLibrary.ParameterVariables(target.Context)
+  if err != nil { return }
+
+  target.Variables, err = env.ContextVariables(target.ContextDir, contextName, config, target.Context, target.State, parameters, target.DockerComposeFiles)
+  return
+}
+
+/**
+* Returns sorted names of services of generated docker compose files
+*/
+func (target *Target) ServiceNames() (serviceNames []string) {
+  for _, dockerComposeData := range target.DockerComposeFiles {
+    for serviceName := range dockerComposeData.Services {
+      serviceNames = append(serviceNames, serviceName)
+    }
+  }
+  sort.Strings(serviceNames)
+
+  return
+}
+
+/**
+* Returns backend of deploy strategy set in config ('deploy-strategy')
+*/
+func New(target *Target) (Backend, error) {
+  switch target.Config["deploy-strategy"] {
+  case STRATEGY_DOCKER_COMPOSE, "":
+    return &Compose{target: target}, nil
+  case STRATEGY_KUBERNETES:
+    return &Kubernetes{target}, nil
+  }
+
+  return nil, fmt.Errorf("unknown deploy strategy '%s', supported strategies: %s", target.Config["deploy-strategy"], strings.Join(Strategies, ", "))
+}
+
+/**
+* Returns docker compose project name of context
+*/
+func (target *Target) Project() string {
+  return target.Variables["COMPOSE_PROJECT_NAME"]
+}
+
+/**
+* Returns health check of service (error if service has no check)
+*/
+func (target *Target) Check(serviceName string) (*health.Check, error) {
+  check, ok := target.Checks[serviceName]
+  if !ok {
+    return nil, fmt.Errorf("service '%s' has no health check", serviceName)
+  }
+
+  return check, nil
+}
+
+/**
+* Waits until services with health checks are healthy (every service is checked by probe), prints last log lines
+* of services which are not healthy on timeout
+*/
+func (target *Target) wait(timeout time.Duration, serviceNames []string, probe func(serviceName string, check *health.Check, commandTimeout time.Duration) error, logs func(serviceName string) string) error {
+  if len(serviceNames) == 0 {
+    serviceNames = target.ServiceNames()
+  }
+  serviceNames = health.Services(target.Checks, serviceNames)
+  if len(serviceNames) == 0 { return nil }
+
+  logger.Info("Waiting for %s (timeout %s)\n", strings.Join(serviceNames, ", "), timeout)
+  if target.DryRun { return nil }
+
+  deadline := time.Now().Add(timeout)
+  failed := health.Wait(serviceNames, timeout, func(serviceName string) error {
+    return probe(serviceName, target.Checks[serviceName], commandTimeout(deadline))
+  })
+  if len(failed) == 0 {
+    logger.Info("All services are healthy\n")
+    return nil
+  }
+
+  var failedNames []string
+  for _, serviceName := range serviceNames {
+    err, isFailed := failed[serviceName]
+    if !isFailed { continue }
+
+    failedNames = append(failedNames, serviceName)
+    logger.Warn("service '%s' is not healthy (%s): %s\n", serviceName, target.Checks[serviceName], err)
+    logger.Header(serviceName + " LOGS")
+    fmt.Print(logger.Redact(logs(serviceName)))
+  }
+
+  return fmt.Errorf("services are not healthy after %s: %s", timeout, strings.Join(failedNames, ", "))
+}
+
+/**
+* Returns timeout of health check command: COMMAND_PROBE_TIMEOUT, but not longer than time left until deadline
+*/
+func commandTimeout(deadline time.Time) time.Duration {
+  left := time.Until(deadline)
+  if left > COMMAND_PROBE_TIMEOUT {
+    return COMMAND_PROBE_TIMEOUT
+  }
+  if left < PROBE_TIMEOUT {
+    return PROBE_TIMEOUT
+  }
+
+  return left
+}
+
+/**
+* Runs shell command attached to terminal (prints it in dry run)
+*/
+func (target *Target) run(command string) error {
+  if target.DryRun {
+    fmt.Println(command)
+    return nil
+  }
+
+  logger.Debug("%s\n", command)
+  return exec.Interactive(command)
+}
+
+/**
+* Runs shell command with stdin and stdout connected to reader and writer (prints it in dry run)
+*/
+func (target *Target) stream(command string, stdin io.Reader, stdout io.Writer) error {
+  if target.DryRun {
+    fmt.Println(command)
+    return nil
+  }
+
+  logger.Debug("%s\n", command)
+  return exec.Stream(command, stdin, stdout)
+}
+
+/**
+* Runs shell command and returns its output (prints it in dry run)
+*/
+func (target *Target) output(command string) (string, error) {
+  if target.DryRun {
+    fmt.Println(command)
+    return "", nil
+  }
+
+  logger.Debug("%s\n", command)
+  out, err := exec.Command(command + " 2>&1")
+  if err != nil {
+    return out, fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
+  }
+  return out, nil
+}
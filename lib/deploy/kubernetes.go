@@ -0,0 +1,240 @@
+package deploy
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/env"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/health"
+  "devlab/lib/kubernetes"
+  "devlab/lib/logger"
+  "devlab/lib/secrets"
+  "devlab/lib/util"
+)
+
+/* Folder of context build folder with rendered manifests */
+const KUBERNETES_MANIFESTS_DIR = "kubernetes"
+
+/**
+* Kubernetes strategy: services of generated docker compose files are rendered to manifests and applied
+* to kube context ('kube-context' config value, e.g. minikube) and namespace ('kube-namespace', devlab-<context>
+* by default)
+*/
+type Kubernetes struct {
+  target *Target
+}
+
+func (backend *Kubernetes) Up() (err error) {
+  model, err := backend.Model()
+  if err != nil { return }
+
+  manifests, warnings := kubernetes.Render(model)
+  for _, warning := range warnings {
+    logger.Warn("%s\n", warning)
+  }
+
+  manifestsDir := backend.ManifestsDir()
+  if err = kubernetes.Write(manifestsDir, manifests); err != nil { return }
+  logger.Info("Manifests are written to %s\n", manifestsDir)
+
+  return backend.target.run(fmt.Sprintf("%s apply --prune -l %s -f %s", backend.kubectl(), util.Quote(backend.selector()), util.Quote(manifestsDir)))
+}
+
+func (backend *Kubernetes) Down() error {
+  return backend.target.run(fmt.Sprintf("%s -n %s delete deployment,service,configmap,secret -l %s", backend.kubectl(), util.Quote(backend.namespace()), util.Quote(backend.selector())))
+}
+
+/**
+* Forwards host ports allocated for context to services in cluster (until it is interrupted)
+*/
+func (backend *Kubernetes) Proxy() (err error) {
+  var commands []string
+
+  serviceNames := make([]string, 0, len(backend.target.State.Ports))
+  for serviceName := range backend.target.State.Ports {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  for _, serviceName := range serviceNames {
+    containerPorts := make([]string, 0, len(backend.target.State.Ports[serviceName]))
+    for containerPort := range backend.target.State.Ports[serviceName] {
+      containerPorts = append(containerPorts, containerPort)
+    }
+    sort.Strings(containerPorts)
+
+    for _, containerPort := range containerPorts {
+      number, protocol, _ := strings.Cut(containerPort, "/")
+      if protocol != "" && protocol != "tcp" {
+        logger.Warn("service '%s': port %s couldn't be forwarded, only tcp ports are supported\n", serviceName, containerPort)
+        continue
+      }
+
+      hostPort := backend.target.State.Ports[serviceName][containerPort]
+      logger.Text(fmt.Sprintf("localhost:%d => %s:%s", hostPort, serviceName, number))
+      commands = append(commands, fmt.Sprintf("%s -n %s port-forward %s %d:%s", backend.kubectl(), util.Quote(backend.namespace()), util.Quote("service/" + serviceName), hostPort, number))
+    }
+  }
+
+  if len(commands) == 0 {
+    logger.Info("There are no allocated ports, run 'devlab create-docker-compose %s'\n", backend.target.ContextName)
+    return
+  }
+
+  var waitGroup sync.WaitGroup
+  errs := make([]error, len(commands))
+  for i, command := range commands {
+    waitGroup.Add(1)
+    go func(i int, command string) {
+      defer waitGroup.Done()
+      errs[i] = backend.target.run(command)
+    }(i, command)
+  }
+  waitGroup.Wait()
+
+  for _, err := range errs {
+    if err != nil { return err }
+  }
+
+  return
+}
+
+/**
+* Health checks are rendered as readiness probes, so service is healthy when its deployment is available
+*/
+func (backend *Kubernetes) Wait(timeout time.Duration, serviceNames ...string) error {
+  return backend.target.wait(timeout, serviceNames, backend.probe, func(serviceName string) string {
+    out, _ := exec.Command(fmt.Sprintf("%s -n %s logs %s --tail %d 2>&1", backend.kubectl(), util.Quote(backend.namespace()), util.Quote("deployment/" + serviceName), LOGS_TAIL))
+    return out
+  })
+}
+
+func (backend *Kubernetes) Probe(serviceName string) error {
+  check, err := backend.target.Check(serviceName)
+  if err != nil { return err }
+
+  return backend.probe(serviceName, check, COMMAND_PROBE_TIMEOUT)
+}
+
+func (backend *Kubernetes) Exec(serviceName string, command string) (string, error) {
+  return backend.target.output(fmt.Sprintf("%s -n %s exec %s -- sh -c %s", backend.kubectl(), util.Quote(backend.namespace()), util.Quote("deployment/" + serviceName), util.Quote(command)))
+}
+
+func (backend *Kubernetes) Stream(serviceName string, command string, stdin io.Reader, stdout io.Writer) error {
+  return backend.target.stream(fmt.Sprintf("%s -n %s exec -i %s -- sh -c %s", backend.kubectl(), util.Quote(backend.namespace()), util.Quote("deployment/" + serviceName), util.Quote(command)), stdin, stdout)
+}
+
+func (backend *Kubernetes) probe(serviceName string, check *health.Check, commandTimeout time.Duration) error {
+  out, err := exec.CommandWithTimeout(fmt.Sprintf("%s -n %s get deployment %s -o jsonpath='{.status.availableReplicas}' 2>&1", backend.kubectl(), util.Quote(backend.namespace()), util.Quote(serviceName)), commandTimeout)
+  if err != nil {
+    return fmt.Errorf("%s %s", err, strings.TrimSpace(out))
+  }
+
+  if available, _ := strconv.Atoi(strings.TrimSpace(out)); available < 1 {
+    return fmt.Errorf("deployment has no available replicas")
+  }
+  return nil
+}
+
+/**
+* Returns kubernetes model of services of generated docker compose files: variables are expanded,
+* environment is read from env files and environment of services, values with secrets are separated
+*/
+func (backend *Kubernetes) Model() (model *kubernetes.Model, err error) {
+  model = &kubernetes.Model{
+    Namespace: backend.namespace(),
+    Project: backend.target.Project(),
+    Services: make(map[string]*DockerComposeFileBuilder.Service),
+    Environment: make(map[string]map[string]string),
+    Secrets: make(map[string]map[string]string),
+    Checks: backend.target.Checks}
+
+  for _, dockerComposeData := range backend.target.DockerComposeFiles {
+    for serviceName, composeService := range dockerComposeData.Services {
+      service := *composeService
+      if service.Image, err = backend.expand(serviceName, service.Image); err != nil { return }
+
+      environment := make(map[string]string)
+      for _, envFile := range service.EnvFile {
+        path, err := backend.expand(serviceName, envFile)
+        if err != nil { return nil, err }
+
+        if isExists, _ := files.IsExists(path); !isExists { continue }
+        content, err := files.ReadTextFile(path)
+        if err != nil { return nil, err }
+
+        for name, value := range env.Parse(content) {
+          environment[name] = value
+        }
+      }
+      for name, value := range service.Environment {
+        /* variable without value is passed from environment of devlab like docker compose does */
+        if value == nil {
+          if hostValue, isSet := os.LookupEnv(name); isSet {
+            environment[name] = hostValue
+          }
+          continue
+        }
+        if environment[name], err = backend.expand(serviceName, *value); err != nil { return }
+      }
+
+      model.Environment[serviceName] = make(map[string]string)
+      model.Secrets[serviceName] = make(map[string]string)
+      for name, value := range environment {
+        if secrets.IsSecret(value) {
+          model.Secrets[serviceName][name] = value
+        } else {
+          model.Environment[serviceName][name] = value
+        }
+      }
+
+      model.Services[serviceName] = &service
+    }
+  }
+
+  return
+}
+
+/**
+* Returns folder with rendered manifests of context
+*/
+func (backend *Kubernetes) ManifestsDir() string {
+  return backend.target.ContextDir + "/" + env.BUILD_DIR + "/" + KUBERNETES_MANIFESTS_DIR
+}
+
+func (backend *Kubernetes) expand(serviceName string, value string) (string, error) {
+  expanded, err := env.Expand(value, backend.target.Variables)
+  if err != nil {
+    return "", fmt.Errorf("service '%s': %s", serviceName, err)
+  }
+
+  return expanded, nil
+}
+
+func (backend *Kubernetes) namespace() string {
+  if namespace := backend.target.Config["kube-namespace"]; namespace != "" {
+    return namespace
+  }
+
+  return backend.target.Project()
+}
+
+func (backend *Kubernetes) selector() string {
+  return kubernetes.PART_OF_LABEL + "=" + backend.target.Project()
+}
+
+func (backend *Kubernetes) kubectl() string {
+  if kubeContext := backend.target.Config["kube-context"]; kubeContext != "" {
+    return "kubectl --context " + util.Quote(kubeContext)
+  }
+
+  return "kubectl"
+}
@@ -0,0 +1,181 @@
+package DockerComposeFileBuilder
+
+import (
+  "fmt"
+  "sort"
+  "devlab/lib/files"
+  "devlab/lib/util"
+)
+
+/* Conventional name of compose fragment in service repository */
+const SERVICE_COMPOSE_FRAGMENT = "devlab.compose.yml"
+
+/* Context level overrides of generated application compose */
+const APPLICATION_COMPOSE_OVERRIDE = "docker-compose.application.override.yml"
+
+/* Labels of application containers (log shippers add them to every log line as service and context fields) */
+const SERVICE_LABEL = "devlab.service"
+const CONTEXT_LABEL = "devlab.context"
+
+const APPLICATION_COMPOSE = "docker-compose.application.yml"
+const SYSTEM_COMPOSE = "docker-compose.system.yml"
+
+/**
+* Builds application docker compose of context. Every enabled application service is defined by
+* (every next source overrides previous one, see Merge):
+*   1. generated defaults (image, env file, volume with service sources, service and context labels)
+*   2. compose fragment in service repository: file set in 'docker-compose' service param of settings.yml
+*      or devlab.compose.yml, relative paths in it are relative to service repository
+*   3. context overrides in <context>/docker-compose.application.override.yml
+* Fragment of service repository defines only its own service: other services are ignored, networks and volumes
+* are added if they are not defined yet (warnings are returned)
+*/
+func ApplicationCompose(contextDir string, contextName string, context map[string]map[string]map[string]string) (dockerComposeData *DockerComposeFile, warnings []string, err error) {
+  dockerComposeData = New("2")
+  dockerComposeData.Networks["default"] = &Network{Name: ContextNetwork(contextName, context), External: true}
+
+  serviceNames := make([]string, 0, len(context["applicaton-services"]))
+  for serviceName := range context["applicaton-services"] {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  for _, serviceName := range serviceNames {
+    serviceParams := context["applicaton-services"][serviceName]
+    if serviceParams["enabled"] == "false" { continue }
+
+    dockerComposeData.Services[serviceName] = &Service{
+      Image: "${IMAGES_PREFIX}" + serviceName,
+      EnvFile: []string{"${BUILD_DIR}/" + serviceName + "/.env"},
+      Volumes: []string{"${DEVENV_ROOT_DIR}/" + serviceName + ":/usr/src/app"},
+      Labels: NewKeyValues(map[string]string{SERVICE_LABEL: serviceName, CONTEXT_LABEL: contextName}),
+      Restart: "always"}
+
+    fragment, err := readServiceFragment(contextDir, serviceName, serviceParams["docker-compose"])
+    if err != nil { return nil, nil, err }
+    if fragment != nil {
+      warnings = append(warnings, mergeServiceFragment(dockerComposeData, serviceName, fragment)...)
+    }
+  }
+
+  overridePath := contextDir + "/" + APPLICATION_COMPOSE_OVERRIDE
+  isOverrideExists, _ := files.IsExists(overridePath)
+  if isOverrideExists {
+    override, err := ReadFile(overridePath)
+    if err != nil { return nil, nil, err }
+
+    Merge(dockerComposeData, override)
+  }
+
+  return
+}
+
+/**
+* Reads generated system and application docker compose files of context (in this order)
+*/
+func ReadGenerated(contextDir string, contextName string) (dockerComposeFiles []*DockerComposeFile, err error) {
+  for _, dockerComposeFile := range []string{SYSTEM_COMPOSE, APPLICATION_COMPOSE} {
+    path := contextDir + "/" + dockerComposeFile
+    if isExists, _ := files.IsExists(path); !isExists {
+      return nil, fmt.Errorf("%s is not found, run 'devlab create-docker-compose %s'", path, contextName)
+    }
+
+    dockerComposeData, err := ReadFile(path)
+    if err != nil { return nil, err }
+    dockerComposeFiles = append(dockerComposeFiles, dockerComposeData)
+  }
+
+  return
+}
+
+/**
+* Returns name of docker network shared by application and system services of context
+*/
+func ContextNetwork(contextName string, context map[string]map[string]map[string]string) string {
+  networkName := context["context"]["docker"]["network"]
+  if networkName == "" {
+    networkName = "devlab-" + contextName
+  }
+
+  return networkName
+}
+
+/**
+* Merges service of compose fragment of its repository, networks and volumes of fragment are added
+* only if they are not defined (they could be shared by services of other teams). Returns warnings about ignored definitions
+*/
+func mergeServiceFragment(dockerComposeData *DockerComposeFile, serviceName string, fragment *DockerComposeFile) (warnings []string) {
+  for _, fragmentServiceName := range util.SortedKeys(fragment.Services) {
+    if fragmentServiceName == serviceName {
+      MergeService(dockerComposeData.Services[serviceName], fragment.Services[serviceName])
+      continue
+    }
+    warnings = append(warnings, fmt.Sprintf("service '%s': service '%s' of docker compose fragment is ignored, fragment could define only its own service", serviceName, fragmentServiceName))
+  }
+
+  for _, networkName := range util.SortedKeys(fragment.Networks) {
+    if _, isDefined := dockerComposeData.Networks[networkName]; isDefined {
+      warnings = append(warnings, fmt.Sprintf("service '%s': network '%s' of docker compose fragment is ignored, it is already defined", serviceName, networkName))
+      continue
+    }
+    dockerComposeData.Networks[networkName] = fragment.Networks[networkName]
+  }
+
+  for _, volumeName := range util.SortedKeys(fragment.Volumes) {
+    if _, isDefined := dockerComposeData.Volumes[volumeName]; isDefined {
+      warnings = append(warnings, fmt.Sprintf("service '%s': volume '%s' of docker compose fragment is ignored, it is already defined", serviceName, volumeName))
+      continue
+    }
+    dockerComposeData.Volumes[volumeName] = fragment.Volumes[volumeName]
+  }
+
+  return
+}
+
+/**
+* Reads compose fragment of service repository (nil if service has no fragment)
+*/
+func readServiceFragment(contextDir string, serviceName string, fragmentName string) (*DockerComposeFile, error) {
+  isRequired := fragmentName != ""
+  if !isRequired {
+    fragmentName = SERVICE_COMPOSE_FRAGMENT
+  }
+
+  /* service is not cloned yet */
+  isServiceDirExists, _ := files.IsExists(contextDir + "/services/" + serviceName)
+  if !isServiceDirExists { return nil, nil }
+
+  serviceDir := "./services/" + serviceName
+  fragmentPath := contextDir + "/services/" + serviceName + "/" + fragmentName
+  isExists, _ := files.IsExists(fragmentPath)
+  if !isExists {
+    if isRequired {
+      return nil, fmt.Errorf("service '%s': docker compose fragment '%s' is not found", serviceName, fragmentPath)
+    }
+    return nil, nil
+  }
+
+  fragment, err := ReadFile(fragmentPath)
+  if err != nil { return nil, err }
+
+  /* version of generated file is chosen by devlab, not by fragments */
+  fragment.Version = ""
+  RebasePaths(fragment, serviceDir)
+
+  return fragment, nil
+}
+
+/**
+* Reads and parses docker compose file
+*/
+func ReadFile(path string) (*DockerComposeFile, error) {
+  data, err := files.ReadTextFile(path)
+  if err != nil { return nil, err }
+
+  dockerComposeData, err := Parse(data)
+  if err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+
+  return dockerComposeData, nil
+}
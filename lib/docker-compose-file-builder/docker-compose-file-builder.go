@@ -2,76 +2,38 @@ package DockerComposeFileBuilder
 
 import (
   "devlab/lib/files"
+  "devlab/lib/yml"
 )
 
-type Service struct {
-  image string
-  env_files []string
-  volumes []string
-  ports []string
-  restart string    
+/**
+* Creates empty docker compose file of the version
+*/
+func New(version string) *DockerComposeFile {
+  return &DockerComposeFile{
+    Version: version,
+    Services: make(map[string]*Service),
+    Networks: make(map[string]*Network),
+    Volumes: make(map[string]*Volume)}
 }
 
-type DockerComposeFile struct {
-  version string
-  services map[string]Service
-  networks map[string]map[string]map[string]string
-}
+/**
+* Validates docker compose file, renders it and writes atomically (see files.WriteGeneratedFile)
+*/
+func Create(dockerComposeFilePath string, dockerComposeData *DockerComposeFile, force bool) error {
+  if err := Validate(dockerComposeData); err != nil {
+    return err
+  }
 
-func CreateDockerComposeObjectExample() *DockerComposeFile {
-	dockerComposeExample := new(DockerComposeFile)
-  dockerComposeExample.version = "2"
-  dockerComposeExample.services = make(map[string]Service)
-  dockerComposeExample.services["dlp-service-config"] = Service{
-    image: "${IMAGES_PREFIX}dlp-service-config",
-    env_files: []string{"${BUILD_DIR}/dlp-service-config/.env"},
-    volumes: []string{"${DEVENV_ROOT_DIR}/dlp-service-config:/usr/src/app"},
-    ports: []string{"4004"},
-    restart: "always"}
+  text, err := Render(dockerComposeData)
+  if err != nil { return err }
 
-	dockerComposeExample.networks = map[string]map[string]map[string]string{ "default": {"external": { "name": "bedrock" } } }
-	
-	return dockerComposeExample
+  return files.WriteGeneratedFile(dockerComposeFilePath, text, force)
 }
 
-
-func Create(dockerComposeFilePath string, dockerComposeData *DockerComposeFile) {     
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "version: " + dockerComposeData.version, 0)
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "services: ", 0)
-
-  for serviceName, serviceData := range dockerComposeData.services {
-    files.WriteAppendFileWithIndent(dockerComposeFilePath, serviceName + ":", 2)
-    
-    files.WriteAppendFileWithIndent(dockerComposeFilePath, "image: " + serviceData.image, 4)
-    
-    if len(serviceData.env_files) > 0 {
-      files.WriteAppendFileWithIndent(dockerComposeFilePath, "env_files: ", 4)
-      for _, envFile := range serviceData.env_files {
-        files.WriteAppendFileWithIndent(dockerComposeFilePath, "- " + envFile, 6)
-      }
-    }
-
-    if len(serviceData.volumes) > 0 {
-      files.WriteAppendFileWithIndent(dockerComposeFilePath, "volumes: ", 4)
-      for _, volume := range serviceData.volumes {
-        files.WriteAppendFileWithIndent(dockerComposeFilePath, "- " + volume, 6)
-      }
-    }
-
-    if len(serviceData.ports) > 0 {
-      files.WriteAppendFileWithIndent(dockerComposeFilePath, "ports: ", 4)
-      for _, port := range serviceData.ports {
-        files.WriteAppendFileWithIndent(dockerComposeFilePath, "- " + port, 6)
-      }
-    }        
-     
-    if serviceData.restart != "" {
-      files.WriteAppendFileWithIndent(dockerComposeFilePath, "restart: " + serviceData.restart, 4)
-    } 
-  }
-
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "networks: ", 0)
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "default: ", 2)
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "external: ", 4)
-  files.WriteAppendFileWithIndent(dockerComposeFilePath, "name: " + dockerComposeData.networks["default"]["external"]["name"] , 6)
-}
\ No newline at end of file
+/**
+* Renders docker compose file to string
+*/
+func Render(dockerComposeData *DockerComposeFile) (string, error) {
+  data, err := yml.Marshal(dockerComposeData)
+  return string(data), err
+}
@@ -0,0 +1,185 @@
+package DockerComposeFileBuilder
+
+import (
+  "path"
+  "path/filepath"
+  "strings"
+)
+
+/**
+* Merges override into base following docker compose override rules:
+* single values are replaced, lists (ports, links, env_file, ...) are appended without duplicates,
+* environment and labels are merged by key, volumes are merged by container path
+*/
+func Merge(base *DockerComposeFile, override *DockerComposeFile) {
+  if override.Version != "" {
+    base.Version = override.Version
+  }
+
+  if len(override.Services) > 0 && base.Services == nil {
+    base.Services = make(map[string]*Service)
+  }
+  for serviceName, overrideService := range override.Services {
+    if baseService, ok := base.Services[serviceName]; ok {
+      MergeService(baseService, overrideService)
+    } else {
+      base.Services[serviceName] = overrideService
+    }
+  }
+
+  if len(override.Networks) > 0 && base.Networks == nil {
+    base.Networks = make(map[string]*Network)
+  }
+  for networkName, network := range override.Networks {
+    base.Networks[networkName] = network
+  }
+
+  if len(override.Volumes) > 0 && base.Volumes == nil {
+    base.Volumes = make(map[string]*Volume)
+  }
+  for volumeName, volume := range override.Volumes {
+    base.Volumes[volumeName] = volume
+  }
+}
+
+/**
+* Merges override service definition into base one (see Merge)
+*/
+func MergeService(base *Service, override *Service) {
+  mergeString(&base.Image, override.Image)
+  mergeString(&base.ContainerName, override.ContainerName)
+  mergeString(&base.Hostname, override.Hostname)
+  mergeString(&base.WorkingDir, override.WorkingDir)
+  mergeString(&base.User, override.User)
+  mergeString(&base.Restart, override.Restart)
+  mergeString(&base.MemLimit, override.MemLimit)
+  mergeString(&base.MemReservation, override.MemReservation)
+  mergeString(&base.Cpus, override.Cpus)
+
+  if override.Build != nil { base.Build = override.Build }
+  if override.Command != nil { base.Command = override.Command }
+  if override.Entrypoint != nil { base.Entrypoint = override.Entrypoint }
+  if override.Healthcheck != nil { base.Healthcheck = override.Healthcheck }
+  if override.Logging != nil { base.Logging = override.Logging }
+  if override.Deploy != nil { base.Deploy = override.Deploy }
+
+  base.EnvFile = appendUnique(base.EnvFile, override.EnvFile)
+  base.Ports = appendUnique(base.Ports, override.Ports)
+  base.Expose = appendUnique(base.Expose, override.Expose)
+  base.Links = appendUnique(base.Links, override.Links)
+  base.ExtraHosts = appendUnique(base.ExtraHosts, override.ExtraHosts)
+  base.Volumes = mergeVolumes(base.Volumes, override.Volumes)
+
+  base.Environment = mergeKeyValues(base.Environment, override.Environment)
+  base.Labels = mergeKeyValues(base.Labels, override.Labels)
+
+  if len(override.DependsOn) > 0 && base.DependsOn == nil {
+    base.DependsOn = make(DependsOn)
+  }
+  for dependencyName, dependency := range override.DependsOn {
+    base.DependsOn[dependencyName] = dependency
+  }
+
+  if len(override.Networks) > 0 && base.Networks == nil {
+    base.Networks = make(ServiceNetworks)
+  }
+  for networkName, network := range override.Networks {
+    base.Networks[networkName] = network
+  }
+}
+
+/**
+* Rewrites relative paths (volumes, env files, build context) of compose file found in dir,
+* so they stay correct in compose file written to other folder
+*/
+func RebasePaths(dockerComposeData *DockerComposeFile, dir string) {
+  rebase := func(relativePath string) string {
+    if relativePath == "." || strings.HasPrefix(relativePath, "./") || strings.HasPrefix(relativePath, "../") {
+      return "./" + path.Join(dir, relativePath)
+    }
+    return relativePath
+  }
+  /* env files and build context are always paths ('elasticsearch/' is a folder, not a named volume) */
+  rebasePath := func(relativePath string) string {
+    if relativePath == "" || filepath.IsAbs(relativePath) || strings.Contains(relativePath, "://") || strings.HasPrefix(relativePath, "git@") || strings.HasPrefix(relativePath, "$") {
+      return relativePath
+    }
+    return rebase("./" + strings.TrimPrefix(relativePath, "./"))
+  }
+
+  for _, service := range dockerComposeData.Services {
+    for i, volume := range service.Volumes {
+      source, target, isMount := strings.Cut(volume, ":")
+      if isMount {
+        service.Volumes[i] = rebase(source) + ":" + target
+      }
+    }
+
+    for i, envFile := range service.EnvFile {
+      service.EnvFile[i] = rebasePath(envFile)
+    }
+
+    if service.Build != nil {
+      service.Build.Context = rebasePath(service.Build.Context)
+    }
+  }
+}
+
+func mergeString(base *string, override string) {
+  if override != "" {
+    *base = override
+  }
+}
+
+func appendUnique(base []string, override []string) []string {
+  for _, item := range override {
+    isFound := false
+    for _, baseItem := range base {
+      isFound = isFound || baseItem == item
+    }
+    if !isFound {
+      base = append(base, item)
+    }
+  }
+
+  return base
+}
+
+func mergeKeyValues(base KeyValues, override KeyValues) KeyValues {
+  if len(override) > 0 && base == nil {
+    base = make(KeyValues)
+  }
+  for key, value := range override {
+    base[key] = value
+  }
+
+  return base
+}
+
+/**
+* Volumes are merged by container path: override volume replaces base volume mounted to the same path
+*/
+func mergeVolumes(base []string, override []string) []string {
+  containerPath := func(volume string) string {
+    parts := strings.Split(volume, ":")
+    if len(parts) == 1 {
+      return parts[0]
+    }
+    return parts[1]
+  }
+
+  for _, volume := range override {
+    isReplaced := false
+    for i, baseVolume := range base {
+      if containerPath(baseVolume) == containerPath(volume) {
+        base[i] = volume
+        isReplaced = true
+      }
+    }
+    if !isReplaced {
+      base = append(base, volume)
+    }
+  }
+
+  return base
+}
@@ -0,0 +1,147 @@
+package DockerComposeFileBuilder
+
+import (
+  "os"
+  "path/filepath"
+  "reflect"
+  "testing"
+)
+
+func TestMerge(t *testing.T) {
+  base, err := Parse(`services:
+  api:
+    image: api
+    command: npm start
+    ports:
+      - "8080"
+    environment:
+      LOG_LEVEL: info
+      PORT: "8080"
+    volumes:
+      - ./api:/usr/src/app
+      - logs:/var/log
+`)
+  if err != nil { t.Fatal(err) }
+  /* file without networks and volumes */
+  base.Networks, base.Volumes = nil, nil
+
+  override, err := Parse(`services:
+  api:
+    image: api:debug
+    ports:
+      - "8080"
+      - "9229"
+    environment:
+      - LOG_LEVEL=debug
+      - HOME
+    volumes:
+      - ./api/src:/usr/src/app
+  worker:
+    image: worker
+networks:
+  backend:
+volumes:
+  logs:
+`)
+  if err != nil { t.Fatal(err) }
+
+  Merge(base, override)
+
+  api := base.Services["api"]
+  if api.Image != "api:debug" || api.Command != "npm start" {
+    t.Errorf("single values should be replaced only if they are set: image '%s', command '%v'", api.Image, api.Command)
+  }
+  if expected := []string{"8080", "9229"}; !reflect.DeepEqual(api.Ports, expected) {
+    t.Errorf("lists should be appended without duplicates: expected %v, got %v", expected, api.Ports)
+  }
+  if expected := []string{"./api/src:/usr/src/app", "logs:/var/log"}; !reflect.DeepEqual(api.Volumes, expected) {
+    t.Errorf("volumes should be merged by container path: expected %v, got %v", expected, api.Volumes)
+  }
+  if *api.Environment["LOG_LEVEL"] != "debug" || *api.Environment["PORT"] != "8080" || api.Environment["HOME"] != nil {
+    t.Errorf("environment should be merged by key: %v", api.Environment)
+  }
+  if _, ok := base.Services["worker"]; !ok {
+    t.Errorf("new service should be added")
+  }
+  if base.Networks["backend"] == nil || base.Volumes["logs"] == nil {
+    t.Errorf("networks and volumes should be added to file without them: %v %v", base.Networks, base.Volumes)
+  }
+}
+
+/**
+* Service is defined by generated defaults, then by fragment of its repository, then by context override
+*/
+func TestApplicationComposePrecedence(t *testing.T) {
+  contextDir := t.TempDir()
+  writeFile(t, filepath.Join(contextDir, "services", "users", SERVICE_COMPOSE_FRAGMENT), `services:
+  users:
+    image: users:fragment
+    command: node server.js
+    environment:
+      DB_HOST: postgres
+      LOG_LEVEL: info
+  orders:
+    image: hijacked
+networks:
+  default:
+    name: other
+  users-internal:
+volumes:
+  users_data:
+`)
+  writeFile(t, filepath.Join(contextDir, "services", "orders", ".keep"), "")
+  writeFile(t, filepath.Join(contextDir, APPLICATION_COMPOSE_OVERRIDE), `services:
+  users:
+    environment:
+      LOG_LEVEL: debug
+  orders:
+    image: orders:override
+`)
+
+  context := map[string]map[string]map[string]string{
+    "applicaton-services": {"users": {}, "orders": {}, "disabled": {"enabled": "false"}},
+  }
+  dockerComposeData, warnings, err := ApplicationCompose(contextDir, "foo", context)
+  if err != nil { t.Fatal(err) }
+
+  if _, ok := dockerComposeData.Services["disabled"]; ok {
+    t.Errorf("disabled service should not be defined")
+  }
+
+  users := dockerComposeData.Services["users"]
+  if users.Image != "users:fragment" || users.Command != "node server.js" {
+    t.Errorf("fragment should override defaults: image '%s', command '%v'", users.Image, users.Command)
+  }
+  if users.Restart != "always" || len(users.EnvFile) != 1 || *users.Labels[SERVICE_LABEL] != "users" {
+    t.Errorf("defaults not set by fragment should be kept: %+v", users)
+  }
+  if *users.Environment["DB_HOST"] != "postgres" || *users.Environment["LOG_LEVEL"] != "debug" {
+    t.Errorf("context override should override fragment: %v", users.Environment)
+  }
+  if expected := []string{"${DEVENV_ROOT_DIR}/users:/usr/src/app"}; !reflect.DeepEqual(users.Volumes, expected) {
+    t.Errorf("expected volumes %v, got %v", expected, users.Volumes)
+  }
+
+  if orders := dockerComposeData.Services["orders"]; orders.Image != "orders:override" {
+    t.Errorf("fragment of users should not define orders, context override should: image '%s'", orders.Image)
+  }
+  if network := dockerComposeData.Networks["default"]; network.Name != "devlab-foo" || !network.External {
+    t.Errorf("fragment should not override network of context: %+v", network)
+  }
+  if dockerComposeData.Networks["users-internal"] == nil || dockerComposeData.Volumes["users_data"] == nil {
+    t.Errorf("new networks and volumes of fragment should be added")
+  }
+
+  expectedWarnings := []string{
+    "service 'users': service 'orders' of docker compose fragment is ignored, fragment could define only its own service",
+    "service 'users': network 'default' of docker compose fragment is ignored, it is already defined",
+  }
+  if !reflect.DeepEqual(warnings, expectedWarnings) {
+    t.Errorf("expected warnings:\n%v\ngot:\n%v", expectedWarnings, warnings)
+  }
+}
+
+func writeFile(t *testing.T, path string, content string) {
+  if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { t.Fatal(err) }
+  if err := os.WriteFile(path, []byte(content), 0644); err != nil { t.Fatal(err) }
+}
@@ -0,0 +1,333 @@
+package DockerComposeFileBuilder
+
+import (
+  "sort"
+  "strings"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+/**
+* Model of docker compose file (https://docs.docker.com/compose/compose-file/)
+*/
+type DockerComposeFile struct {
+  Version string `yaml:"version,omitempty"`
+  Services map[string]*Service `yaml:"services"`
+  Networks map[string]*Network `yaml:"networks,omitempty"`
+  Volumes map[string]*Volume `yaml:"volumes,omitempty"`
+}
+
+type Service struct {
+  Image string `yaml:"image,omitempty"`
+  Build *Build `yaml:"build,omitempty"`
+  ContainerName string `yaml:"container_name,omitempty"`
+  Hostname string `yaml:"hostname,omitempty"`
+  Command interface{} `yaml:"command,omitempty"`
+  Entrypoint interface{} `yaml:"entrypoint,omitempty"`
+  WorkingDir string `yaml:"working_dir,omitempty"`
+  User string `yaml:"user,omitempty"`
+  EnvFile []string `yaml:"env_file,omitempty"`
+  Environment KeyValues `yaml:"environment,omitempty"`
+  Volumes []string `yaml:"volumes,omitempty"`
+  Ports []string `yaml:"ports,omitempty"`
+  Expose []string `yaml:"expose,omitempty"`
+  DependsOn DependsOn `yaml:"depends_on,omitempty"`
+  Links []string `yaml:"links,omitempty"`
+  Healthcheck *Healthcheck `yaml:"healthcheck,omitempty"`
+  Labels KeyValues `yaml:"labels,omitempty"`
+  Networks ServiceNetworks `yaml:"networks,omitempty"`
+  ExtraHosts []string `yaml:"extra_hosts,omitempty"`
+  Logging *Logging `yaml:"logging,omitempty"`
+  Deploy *Deploy `yaml:"deploy,omitempty"`
+  MemLimit string `yaml:"mem_limit,omitempty"`
+  MemReservation string `yaml:"mem_reservation,omitempty"`
+  Cpus string `yaml:"cpus,omitempty"`
+  Restart string `yaml:"restart,omitempty"`
+}
+
+type Build struct {
+  Context string `yaml:"context,omitempty"`
+  Dockerfile string `yaml:"dockerfile,omitempty"`
+  Args KeyValues `yaml:"args,omitempty"`
+  Target string `yaml:"target,omitempty"`
+}
+
+type Dependency struct {
+  Condition string `yaml:"condition,omitempty"`
+}
+
+type Healthcheck struct {
+  Test interface{} `yaml:"test,omitempty"`
+  Interval string `yaml:"interval,omitempty"`
+  Timeout string `yaml:"timeout,omitempty"`
+  Retries int `yaml:"retries,omitempty"`
+  StartPeriod string `yaml:"start_period,omitempty"`
+  Disable bool `yaml:"disable,omitempty"`
+}
+
+type ServiceNetwork struct {
+  Aliases []string `yaml:"aliases,omitempty"`
+  Ipv4Address string `yaml:"ipv4_address,omitempty"`
+}
+
+type Logging struct {
+  Driver string `yaml:"driver,omitempty"`
+  Options KeyValues `yaml:"options,omitempty"`
+}
+
+type Deploy struct {
+  Replicas int `yaml:"replicas,omitempty"`
+  Resources *Resources `yaml:"resources,omitempty"`
+}
+
+type Resources struct {
+  Limits *ResourceValues `yaml:"limits,omitempty"`
+  Reservations *ResourceValues `yaml:"reservations,omitempty"`
+}
+
+type ResourceValues struct {
+  Cpus string `yaml:"cpus,omitempty"`
+  Memory string `yaml:"memory,omitempty"`
+}
+
+type Network struct {
+  Name string `yaml:"name,omitempty"`
+  Driver string `yaml:"driver,omitempty"`
+  External bool `yaml:"external,omitempty"`
+  Labels KeyValues `yaml:"labels,omitempty"`
+
+  /* external network name is written as 'external: { name: <name> }' (see Translate) */
+  legacyExternal bool
+}
+
+type Volume struct {
+  Name string `yaml:"name,omitempty"`
+  Driver string `yaml:"driver,omitempty"`
+  External bool `yaml:"external,omitempty"`
+  Labels KeyValues `yaml:"labels,omitempty"`
+}
+
+/**
+* Map which could be written in compose file as map or as list of 'KEY=VALUE' (environment, labels, args).
+* Key without value ('KEY' in list, 'KEY:' in map) has nil value: docker compose takes it from the shell
+*/
+type KeyValues map[string]*string
+
+/* Dependencies of service, written as list if there are no conditions */
+type DependsOn map[string]Dependency
+
+/* Networks of service, written as list if there are no aliases or addresses */
+type ServiceNetworks map[string]*ServiceNetwork
+
+/**
+* Parses docker compose file
+*/
+func Parse(data string) (dockerComposeData *DockerComposeFile, err error) {
+  dockerComposeData = New("")
+  if err = yamlv3.Unmarshal([]byte(data), dockerComposeData); err != nil { return }
+
+  /* 'name:' without value is the common way to declare service, network or volume with defaults */
+  for serviceName, service := range dockerComposeData.Services {
+    if service == nil {
+      dockerComposeData.Services[serviceName] = &Service{}
+    }
+  }
+  for networkName, network := range dockerComposeData.Networks {
+    if network == nil {
+      dockerComposeData.Networks[networkName] = &Network{}
+    }
+  }
+  for volumeName, volume := range dockerComposeData.Volumes {
+    if volume == nil {
+      dockerComposeData.Volumes[volumeName] = &Volume{}
+    }
+  }
+
+  return
+}
+
+/**
+* Returns key values with all values set
+*/
+func NewKeyValues(values map[string]string) KeyValues {
+  keyValues := make(KeyValues)
+  for key, value := range values {
+    value := value
+    keyValues[key] = &value
+  }
+
+  return keyValues
+}
+
+func (values *KeyValues) UnmarshalYAML(node *yamlv3.Node) error {
+  *values = make(KeyValues)
+
+  if node.Kind == yamlv3.SequenceNode {
+    var list []string
+    if err := node.Decode(&list); err != nil { return err }
+
+    for _, item := range list {
+      key, value, hasValue := strings.Cut(item, "=")
+      (*values)[key] = nil
+      if hasValue {
+        (*values)[key] = &value
+      }
+    }
+    return nil
+  }
+
+  var mapping map[string]*string
+  if err := node.Decode(&mapping); err != nil { return err }
+
+  for key, value := range mapping {
+    (*values)[key] = value
+  }
+  return nil
+}
+
+func (dependsOn *DependsOn) UnmarshalYAML(node *yamlv3.Node) error {
+  *dependsOn = make(DependsOn)
+
+  if node.Kind == yamlv3.SequenceNode {
+    var list []string
+    if err := node.Decode(&list); err != nil { return err }
+
+    for _, serviceName := range list {
+      (*dependsOn)[serviceName] = Dependency{}
+    }
+    return nil
+  }
+
+  var mapping map[string]Dependency
+  if err := node.Decode(&mapping); err != nil { return err }
+
+  for serviceName, dependency := range mapping {
+    (*dependsOn)[serviceName] = dependency
+  }
+  return nil
+}
+
+func (dependsOn DependsOn) MarshalYAML() (interface{}, error) {
+  if dependsOn.HasConditions() {
+    return map[string]Dependency(dependsOn), nil
+  }
+
+  return dependsOn.Names(), nil
+}
+
+/**
+* Returns names of dependencies in alphabetical order
+*/
+func (dependsOn DependsOn) Names() (names []string) {
+  for serviceName := range dependsOn {
+    names = append(names, serviceName)
+  }
+  sort.Strings(names)
+
+  return
+}
+
+func (dependsOn DependsOn) HasConditions() bool {
+  for _, dependency := range dependsOn {
+    if dependency.Condition != "" {
+      return true
+    }
+  }
+
+  return false
+}
+
+func (networks *ServiceNetworks) UnmarshalYAML(node *yamlv3.Node) error {
+  *networks = make(ServiceNetworks)
+
+  if node.Kind == yamlv3.SequenceNode {
+    var list []string
+    if err := node.Decode(&list); err != nil { return err }
+
+    for _, networkName := range list {
+      (*networks)[networkName] = nil
+    }
+    return nil
+  }
+
+  var mapping map[string]*ServiceNetwork
+  if err := node.Decode(&mapping); err != nil { return err }
+
+  for networkName, network := range mapping {
+    (*networks)[networkName] = network
+  }
+  return nil
+}
+
+func (networks ServiceNetworks) MarshalYAML() (interface{}, error) {
+  var names []string
+  hasSettings := false
+  for networkName, network := range networks {
+    names = append(names, networkName)
+    hasSettings = hasSettings || network != nil
+  }
+  sort.Strings(names)
+
+  if hasSettings {
+    withSettings := make(map[string]*ServiceNetwork)
+    for networkName, network := range networks {
+      if network == nil {
+        network = &ServiceNetwork{}
+      }
+      withSettings[networkName] = network
+    }
+    return withSettings, nil
+  }
+
+  return names, nil
+}
+
+func (build *Build) UnmarshalYAML(node *yamlv3.Node) error {
+  if node.Kind == yamlv3.ScalarNode {
+    build.Context = node.Value
+    return nil
+  }
+
+  type plainBuild Build
+  return node.Decode((*plainBuild)(build))
+}
+
+/**
+* External network is written in legacy form 'external: { name: <name> }' for old file versions
+*/
+func (network Network) MarshalYAML() (interface{}, error) {
+  type plainNetwork Network
+
+  if network.legacyExternal && network.External && network.Name != "" {
+    return struct {
+      External map[string]string `yaml:"external"`
+    }{map[string]string{"name": network.Name}}, nil
+  }
+
+  return plainNetwork(network), nil
+}
+
+func (network *Network) UnmarshalYAML(node *yamlv3.Node) error {
+  type plainNetwork Network
+  var legacy struct {
+    External map[string]string `yaml:"external"`
+  }
+
+  /* legacy 'external: { name: <name> }' form */
+  if external := externalNode(node); external != nil && external.Kind == yamlv3.MappingNode {
+    if err := node.Decode(&legacy); err != nil { return err }
+    network.External, network.Name = true, legacy.External["name"]
+    return nil
+  }
+
+  return node.Decode((*plainNetwork)(network))
+}
+
+func externalNode(node *yamlv3.Node) *yamlv3.Node {
+  for i := 0; i + 1 < len(node.Content); i += 2 {
+    if node.Content[i].Value == "external" {
+      return node.Content[i + 1]
+    }
+  }
+
+  return nil
+}
@@ -0,0 +1,169 @@
+package DockerComposeFileBuilder
+
+import (
+  "testing"
+)
+
+func TestKeyValuesRoundTrip(t *testing.T) {
+  tests := []struct {
+    name string
+    environment string
+    expected map[string]string
+    /* keys without value, they are taken from the shell by docker compose */
+    passThrough []string
+    rendered string
+  }{
+    {
+      name: "list",
+      environment: "\n      - HOME\n      - FOO=bar\n      - EMPTY=\n      - URL=http://host/?a=b",
+      expected: map[string]string{"FOO": "bar", "EMPTY": "", "URL": "http://host/?a=b"},
+      passThrough: []string{"HOME"},
+      rendered: "\n      EMPTY: \"\"\n      FOO: bar\n      HOME: null\n      URL: http://host/?a=b",
+    },
+    {
+      name: "map",
+      environment: "\n      FOO: bar\n      EMPTY: \"\"\n      PORT: \"5432\"",
+      expected: map[string]string{"FOO": "bar", "EMPTY": "", "PORT": "5432"},
+      rendered: "\n      EMPTY: \"\"\n      FOO: bar\n      PORT: \"5432\"",
+    },
+    {
+      name: "null values",
+      environment: "\n      HOME:\n      USER: null\n      FOO: bar",
+      expected: map[string]string{"FOO": "bar"},
+      passThrough: []string{"HOME", "USER"},
+      rendered: "\n      FOO: bar\n      HOME: null\n      USER: null",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      dockerComposeData, err := Parse("services:\n  api:\n    image: api\n    environment:" + test.environment + "\n")
+      if err != nil { t.Fatal(err) }
+
+      for round := 0; round < 2; round++ {
+        environment := dockerComposeData.Services["api"].Environment
+        if len(environment) != len(test.expected) + len(test.passThrough) {
+          t.Errorf("round %d: expected %d variables, got %d", round, len(test.expected) + len(test.passThrough), len(environment))
+        }
+        for key, value := range test.expected {
+          if environment[key] == nil || *environment[key] != value {
+            t.Errorf("round %d: '%s': expected '%s', got %v", round, key, value, environment[key])
+          }
+        }
+        for _, key := range test.passThrough {
+          if value, ok := environment[key]; !ok || value != nil {
+            t.Errorf("round %d: '%s' should be passed from the shell, got %v", round, key, value)
+          }
+        }
+
+        rendered, err := Render(dockerComposeData)
+        if err != nil { t.Fatal(err) }
+        if expected := "services:\n  api:\n    image: api\n    environment:" + test.rendered + "\n"; rendered != expected {
+          t.Errorf("round %d: expected:\n%s\ngot:\n%s", round, expected, rendered)
+        }
+
+        /* rendered file is parsed to the same model */
+        if dockerComposeData, err = Parse(rendered); err != nil { t.Fatal(err) }
+      }
+    })
+  }
+}
+
+func TestParseAndRender(t *testing.T) {
+  tests := []struct {
+    name string
+    data string
+    /* expected rendered file (the same as data if empty) */
+    rendered string
+    isError bool
+  }{
+    {
+      name: "depends_on without conditions is written as list",
+      data: "services:\n  api:\n    image: api\n    depends_on:\n      db: {}\n      cache:\n  cache:\n    image: redis\n  db:\n    image: postgres\n",
+      rendered: "services:\n  api:\n    image: api\n    depends_on:\n      - cache\n      - db\n  cache:\n    image: redis\n  db:\n    image: postgres\n",
+    },
+    {
+      name: "depends_on with conditions",
+      data: "services:\n  api:\n    image: api\n    depends_on:\n      db:\n        condition: service_healthy\n      migrations:\n        condition: service_completed_successfully\n",
+    },
+    {
+      name: "healthcheck",
+      data: "services:\n  db:\n    image: postgres\n    healthcheck:\n      test:\n        - CMD-SHELL\n        - pg_isready -U postgres\n      interval: 10s\n      timeout: 5s\n      retries: 5\n      start_period: 30s\n",
+    },
+    {
+      name: "disabled healthcheck",
+      data: "services:\n  db:\n    image: postgres\n    healthcheck:\n      disable: true\n",
+    },
+    {
+      name: "short form of service networks",
+      data: "services:\n  api:\n    image: api\n    networks:\n      - frontend\n      - backend\nnetworks:\n  backend:\n  frontend:\n    driver: bridge\n",
+      rendered: "services:\n  api:\n    image: api\n    networks:\n      - backend\n      - frontend\nnetworks:\n  backend: {}\n  frontend:\n    driver: bridge\n",
+    },
+    {
+      name: "long form of service networks",
+      data: "services:\n  api:\n    image: api\n    networks:\n      backend:\n        aliases:\n          - users\n      frontend:\nnetworks:\n  backend: {}\n  frontend: {}\n",
+      rendered: "services:\n  api:\n    image: api\n    networks:\n      backend:\n        aliases:\n          - users\n      frontend: {}\nnetworks:\n  backend: {}\n  frontend: {}\n",
+    },
+    {
+      name: "legacy external network",
+      data: "services:\n  api:\n    image: api\nnetworks:\n  default:\n    external:\n      name: devlab-foo\n",
+      rendered: "services:\n  api:\n    image: api\nnetworks:\n  default:\n    name: devlab-foo\n    external: true\n",
+    },
+    {
+      name: "build as string",
+      data: "services:\n  api:\n    build: ./api\n",
+      rendered: "services:\n  api:\n    build:\n      context: ./api\n",
+    },
+    {
+      name: "build as map",
+      data: "services:\n  api:\n    build:\n      context: ./api\n      dockerfile: Dockerfile.dev\n      args:\n        NODE_ENV: development\n      target: dev\n",
+    },
+    {
+      name: "service without value",
+      data: "services:\n  api:\n",
+      rendered: "services:\n  api: {}\n",
+    },
+    {
+      name: "invalid yaml",
+      data: "services:\n  api: [\n",
+      isError: true,
+    },
+    {
+      name: "wrong type of key",
+      data: "services:\n  api:\n    ports: 8080\n",
+      isError: true,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      dockerComposeData, err := Parse(test.data)
+      if test.isError {
+        if err == nil {
+          t.Errorf("expected error, got %+v", dockerComposeData)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      expected := test.rendered
+      if expected == "" {
+        expected = test.data
+      }
+      rendered, err := Render(dockerComposeData)
+      if err != nil { t.Fatal(err) }
+      if rendered != expected {
+        t.Errorf("expected:\n%s\ngot:\n%s", expected, rendered)
+      }
+
+      /* rendered file is rendered the same way again */
+      reparsed, err := Parse(rendered)
+      if err != nil { t.Fatal(err) }
+      if again, _ := Render(reparsed); again != rendered {
+        t.Errorf("rendering is not stable:\n%s\nthen:\n%s", rendered, again)
+      }
+    })
+  }
+}
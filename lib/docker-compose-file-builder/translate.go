@@ -0,0 +1,168 @@
+package DockerComposeFileBuilder
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+/* Value of 'docker-compose-version' config for unversioned Compose Specification */
+const COMPOSE_SPEC = "spec"
+
+/**
+* Target format of docker compose file: 2.x, 3.x or Compose Specification (major = 0)
+*/
+type FileVersion struct {
+  Major int
+  Minor int
+}
+
+/**
+* Parses 'docker-compose-version' config value ('2', '2.4', '3.7' or 'spec')
+*/
+func ParseFileVersion(version string) (fileVersion FileVersion, err error) {
+  if version == COMPOSE_SPEC || version == "" {
+    return
+  }
+
+  majorString, minorString, _ := strings.Cut(version, ".")
+  fileVersion.Major, err = strconv.Atoi(majorString)
+  if err == nil && minorString != "" {
+    fileVersion.Minor, err = strconv.Atoi(minorString)
+  }
+  if err != nil || (fileVersion.Major != 2 && fileVersion.Major != 3) {
+    return fileVersion, fmt.Errorf("docker compose file version '%s' is not supported (use 2.x, 3.x or '%s')", version, COMPOSE_SPEC)
+  }
+
+  return
+}
+
+func (v FileVersion) IsSpec() bool {
+  return v.Major == 0
+}
+
+/**
+* Checks if version is the same or newer than major.minor of the same major version (spec supports everything)
+*/
+func (v FileVersion) AtLeast(major int, minor int) bool {
+  return v.IsSpec() || (v.Major == major && v.Minor >= minor)
+}
+
+func (v FileVersion) String() string {
+  if v.IsSpec() {
+    return "Compose Specification"
+  }
+  return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+/**
+* Translates docker compose file to the file version: sets version key and rewrites features which differ
+* between versions (depends_on conditions, healthcheck, mem_limit vs deploy.resources, external network syntax).
+* Returns warnings about features which couldn't be expressed in the version (they are dropped)
+*/
+func Translate(dockerComposeData *DockerComposeFile, version string) (warnings []string, err error) {
+  fileVersion, err := ParseFileVersion(version)
+  if err != nil { return }
+
+  warn := func(format string, params ...interface{}) {
+    warnings = append(warnings, fmt.Sprintf(format, params...) + " in docker compose file " + fileVersion.String())
+  }
+
+  dockerComposeData.Version = ""
+  if !fileVersion.IsSpec() {
+    dockerComposeData.Version = fileVersion.String()
+  }
+
+  for serviceName, service := range dockerComposeData.Services {
+    /* depends_on conditions: 2.1+ and spec, not 3.x */
+    if service.DependsOn.HasConditions() && !fileVersion.AtLeast(2, 1) {
+      for dependencyName, dependency := range service.DependsOn {
+        if dependency.Condition != "" {
+          warn("service '%s': condition '%s' of dependency '%s' can't be expressed, plain dependency is used", serviceName, dependency.Condition, dependencyName)
+        }
+        service.DependsOn[dependencyName] = Dependency{}
+      }
+    }
+
+    /* healthcheck: 2.1+, 3.x and spec, start_period: 2.3+, 3.4+ */
+    if service.Healthcheck != nil {
+      if fileVersion.Major == 2 && fileVersion.Minor < 1 {
+        warn("service '%s': healthcheck can't be expressed", serviceName)
+        service.Healthcheck = nil
+      } else if service.Healthcheck.StartPeriod != "" && !fileVersion.AtLeast(2, 3) && !fileVersion.AtLeast(3, 4) {
+        warn("service '%s': healthcheck start_period can't be expressed", serviceName)
+        service.Healthcheck.StartPeriod = ""
+      }
+    }
+
+    translateResources(serviceName, service, fileVersion, warn)
+  }
+
+  /* 'name' of external network: 2.1+, 3.5+ and spec, legacy 'external: { name: <name> }' for others */
+  for _, network := range dockerComposeData.Networks {
+    if network == nil { continue }
+    network.legacyExternal = !fileVersion.AtLeast(2, 1) && !fileVersion.AtLeast(3, 5)
+  }
+
+  return
+}
+
+/**
+* Resources are set by mem_limit/mem_reservation/cpus in 2.x and by deploy.resources in 3.x,
+* spec supports both
+*/
+func translateResources(serviceName string, service *Service, fileVersion FileVersion, warn func(string, ...interface{})) {
+  switch fileVersion.Major {
+  case 2:
+    if service.Deploy != nil {
+      if resources := service.Deploy.Resources; resources != nil {
+        if resources.Limits != nil {
+          mergeString(&service.MemLimit, resources.Limits.Memory)
+          mergeString(&service.Cpus, resources.Limits.Cpus)
+        }
+        if resources.Reservations != nil {
+          mergeString(&service.MemReservation, resources.Reservations.Memory)
+          if resources.Reservations.Cpus != "" {
+            warn("service '%s': cpus reservation can't be expressed", serviceName)
+          }
+        }
+      }
+      if service.Deploy.Replicas > 1 {
+        warn("service '%s': %d replicas can't be expressed", serviceName, service.Deploy.Replicas)
+      }
+      service.Deploy = nil
+    }
+
+    if service.Cpus != "" && !fileVersion.AtLeast(2, 2) {
+      warn("service '%s': cpus limit can't be expressed", serviceName)
+      service.Cpus = ""
+    }
+
+  case 3:
+    if service.MemLimit == "" && service.MemReservation == "" && service.Cpus == "" { return }
+
+    if service.Deploy == nil {
+      service.Deploy = &Deploy{}
+    }
+    if service.Deploy.Resources == nil {
+      service.Deploy.Resources = &Resources{}
+    }
+    resources := service.Deploy.Resources
+
+    if service.MemLimit != "" || service.Cpus != "" {
+      if resources.Limits == nil {
+        resources.Limits = &ResourceValues{}
+      }
+      mergeString(&resources.Limits.Memory, service.MemLimit)
+      mergeString(&resources.Limits.Cpus, service.Cpus)
+    }
+    if service.MemReservation != "" {
+      if resources.Reservations == nil {
+        resources.Reservations = &ResourceValues{}
+      }
+      mergeString(&resources.Reservations.Memory, service.MemReservation)
+    }
+
+    service.MemLimit, service.MemReservation, service.Cpus = "", "", ""
+  }
+}
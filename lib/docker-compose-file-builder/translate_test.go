@@ -0,0 +1,118 @@
+package DockerComposeFileBuilder
+
+import (
+  "testing"
+)
+
+/**
+* Compose file with features which differ between file versions: depends_on condition, healthcheck with
+* start_period, memory limit, external network with name and network and volume declared without value
+*/
+const TRANSLATE_COMPOSE = `services:
+  api:
+    image: api
+    depends_on:
+      db:
+        condition: service_healthy
+    mem_limit: 512m
+    networks:
+      - backend
+  db:
+    image: postgres
+    healthcheck:
+      test: pg_isready
+      start_period: 10s
+    volumes:
+      - pg_data:/var/lib/postgresql/data
+networks:
+  backend:
+  shared:
+    name: devlab-foo
+    external: true
+volumes:
+  pg_data:
+`
+
+func TestTranslate(t *testing.T) {
+  tests := []struct {
+    version string
+    expectedVersion string
+    hasCondition bool
+    hasHealthcheck bool
+    hasStartPeriod bool
+    /* memory limit is set by mem_limit (2.x, spec) or by deploy.resources (3.x) */
+    memLimit string
+    deployMemory string
+    isLegacyExternal bool
+    warnings int
+  }{
+    {version: "2.0", expectedVersion: "2.0", memLimit: "512m", isLegacyExternal: true, warnings: 2},
+    {version: "2.1", expectedVersion: "2.1", hasCondition: true, hasHealthcheck: true, memLimit: "512m", warnings: 1},
+    {version: "3.4", expectedVersion: "3.4", hasHealthcheck: true, hasStartPeriod: true, deployMemory: "512m", isLegacyExternal: true, warnings: 1},
+    {version: "3.5", expectedVersion: "3.5", hasHealthcheck: true, hasStartPeriod: true, deployMemory: "512m", warnings: 1},
+    {version: COMPOSE_SPEC, hasCondition: true, hasHealthcheck: true, hasStartPeriod: true, memLimit: "512m"},
+  }
+
+  for _, test := range tests {
+    t.Run(test.version, func(t *testing.T) {
+      dockerComposeData, err := Parse(TRANSLATE_COMPOSE)
+      if err != nil { t.Fatal(err) }
+
+      warnings, err := Translate(dockerComposeData, test.version)
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      if dockerComposeData.Version != test.expectedVersion {
+        t.Errorf("expected version '%s', got '%s'", test.expectedVersion, dockerComposeData.Version)
+      }
+      if len(warnings) != test.warnings {
+        t.Errorf("expected %d warnings, got %v", test.warnings, warnings)
+      }
+
+      api, db := dockerComposeData.Services["api"], dockerComposeData.Services["db"]
+      if _, ok := api.DependsOn["db"]; !ok {
+        t.Errorf("dependency on db is dropped")
+      }
+      if hasCondition := api.DependsOn["db"].Condition != ""; hasCondition != test.hasCondition {
+        t.Errorf("expected condition %t, got %t", test.hasCondition, hasCondition)
+      }
+      if hasHealthcheck := db.Healthcheck != nil; hasHealthcheck != test.hasHealthcheck {
+        t.Errorf("expected healthcheck %t, got %t", test.hasHealthcheck, hasHealthcheck)
+      }
+      if hasStartPeriod := db.Healthcheck != nil && db.Healthcheck.StartPeriod != ""; hasStartPeriod != test.hasStartPeriod {
+        t.Errorf("expected start_period %t, got %t", test.hasStartPeriod, hasStartPeriod)
+      }
+
+      if api.MemLimit != test.memLimit {
+        t.Errorf("expected mem_limit '%s', got '%s'", test.memLimit, api.MemLimit)
+      }
+      deployMemory := ""
+      if api.Deploy != nil && api.Deploy.Resources != nil && api.Deploy.Resources.Limits != nil {
+        deployMemory = api.Deploy.Resources.Limits.Memory
+      }
+      if deployMemory != test.deployMemory {
+        t.Errorf("expected deploy.resources.limits.memory '%s', got '%s'", test.deployMemory, deployMemory)
+      }
+
+      if dockerComposeData.Networks["backend"] == nil || dockerComposeData.Volumes["pg_data"] == nil {
+        t.Errorf("network and volume declared without value should be kept")
+      }
+      if isLegacy := dockerComposeData.Networks["shared"].legacyExternal; isLegacy != test.isLegacyExternal {
+        t.Errorf("expected legacy external network %t, got %t", test.isLegacyExternal, isLegacy)
+      }
+
+      if _, err = Render(dockerComposeData); err != nil {
+        t.Errorf("couldn't render translated file: %s", err)
+      }
+    })
+  }
+}
+
+func TestTranslateUnsupportedVersion(t *testing.T) {
+  for _, version := range []string{"1", "4.0", "2.x"} {
+    if _, err := Translate(New(""), version); err == nil {
+      t.Errorf("version '%s' should not be supported", version)
+    }
+  }
+}
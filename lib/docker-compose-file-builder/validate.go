@@ -0,0 +1,97 @@
+package DockerComposeFileBuilder
+
+import (
+  "fmt"
+  "regexp"
+  "sort"
+  "strings"
+)
+
+var restartPolicies = map[string]bool{"no": true, "always": true, "on-failure": true, "unless-stopped": true}
+var dependencyConditions = map[string]bool{"service_started": true, "service_healthy": true, "service_completed_successfully": true}
+
+/* [host_ip:][host_port:]container_port[/protocol], ports could be ranges and variables */
+var portPattern = regexp.MustCompile(`^((\d{1,3}(\.\d{1,3}){3}|\$\{[^}]+\}):)?((\d+(-\d+)?|\$\{[^}]+\}):)?(\d+(-\d+)?|\$\{[^}]+\})(/(tcp|udp|sctp))?$`)
+
+/**
+* Error with all problems found in docker compose file
+*/
+type ValidationError struct {
+  Problems []string
+}
+
+func (e *ValidationError) Error() string {
+  return "docker compose file is not valid:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+/**
+* Checks docker compose file against compose spec rules, which are not guaranteed by the model:
+* every service has image or build, references to services, networks and volumes are defined,
+* ports, restart policies and dependency conditions have valid values
+*/
+func Validate(dockerComposeData *DockerComposeFile) error {
+  var problems []string
+  addProblem := func(format string, params ...interface{}) {
+    problems = append(problems, fmt.Sprintf(format, params...))
+  }
+
+  if len(dockerComposeData.Services) == 0 {
+    addProblem("there are no services")
+  }
+
+  for serviceName, service := range dockerComposeData.Services {
+    if service.Image == "" && (service.Build == nil || service.Build.Context == "") {
+      addProblem("service '%s': image or build context should be set", serviceName)
+    }
+
+    for _, port := range service.Ports {
+      if !portPattern.MatchString(port) {
+        addProblem("service '%s': port '%s' is not valid", serviceName, port)
+      }
+    }
+
+    if service.Restart != "" && !restartPolicies[service.Restart] && !strings.HasPrefix(service.Restart, "on-failure:") {
+      addProblem("service '%s': restart policy '%s' is not valid", serviceName, service.Restart)
+    }
+
+    for dependencyName, dependency := range service.DependsOn {
+      if _, ok := dockerComposeData.Services[dependencyName]; !ok {
+        addProblem("service '%s': depends on undefined service '%s'", serviceName, dependencyName)
+      }
+      if dependency.Condition != "" && !dependencyConditions[dependency.Condition] {
+        addProblem("service '%s': dependency condition '%s' is not valid", serviceName, dependency.Condition)
+      }
+      if dependency.Condition == "service_healthy" {
+        if dependencyService, ok := dockerComposeData.Services[dependencyName]; ok && dependencyService.Healthcheck == nil {
+          addProblem("service '%s': depends on healthy '%s', but it has no healthcheck", serviceName, dependencyName)
+        }
+      }
+    }
+
+    for networkName := range service.Networks {
+      if _, ok := dockerComposeData.Networks[networkName]; !ok && networkName != "default" {
+        addProblem("service '%s': network '%s' is not defined", serviceName, networkName)
+      }
+    }
+
+    for _, volume := range service.Volumes {
+      source, _, isMount := strings.Cut(volume, ":")
+      if !isMount || source == "" || strings.ContainsAny(source[:1], "./~$") { continue }
+
+      if _, ok := dockerComposeData.Volumes[source]; !ok {
+        addProblem("service '%s': volume '%s' is not defined", serviceName, source)
+      }
+    }
+
+    if healthcheck := service.Healthcheck; healthcheck != nil && !healthcheck.Disable && healthcheck.Test == nil {
+      addProblem("service '%s': healthcheck test should be set", serviceName)
+    }
+  }
+
+  if len(problems) > 0 {
+    sort.Strings(problems)
+    return &ValidationError{problems}
+  }
+
+  return nil
+}
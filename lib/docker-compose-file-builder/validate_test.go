@@ -0,0 +1,124 @@
+package DockerComposeFileBuilder
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestValidate(t *testing.T) {
+  tests := []struct {
+    name string
+    data string
+    problems []string
+  }{
+    {
+      name: "valid file",
+      data: `services:
+  api:
+    build: ./api
+    ports:
+      - 8080
+      - "20001:8080"
+      - 127.0.0.1:20002:8080/tcp
+      - ${API_PORT}:8080
+      - 9000-9001:9000-9001
+    restart: on-failure:3
+    depends_on:
+      db:
+        condition: service_healthy
+    networks:
+      backend:
+        aliases:
+          - users
+      default:
+    volumes:
+      - ./api:/usr/src/app
+      - pg_data:/data
+      - /tmp:/tmp
+  db:
+    image: postgres
+    healthcheck:
+      test: pg_isready
+networks:
+  backend:
+volumes:
+  pg_data:
+`,
+    },
+    {
+      name: "no services",
+      data: "services: {}\n",
+      problems: []string{"there are no services"},
+    },
+    {
+      name: "service without image and build",
+      data: "services:\n  api:\n    build:\n      dockerfile: Dockerfile\n",
+      problems: []string{"service 'api': image or build context should be set"},
+    },
+    {
+      name: "invalid port and restart policy",
+      data: "services:\n  api:\n    image: api\n    ports:\n      - 80:http\n    restart: sometimes\n",
+      problems: []string{"service 'api': port '80:http' is not valid", "service 'api': restart policy 'sometimes' is not valid"},
+    },
+    {
+      name: "dependencies",
+      data: `services:
+  api:
+    image: api
+    depends_on:
+      db:
+        condition: service_healthy
+      cache:
+        condition: service_ready
+      queue:
+  cache:
+    image: redis
+  db:
+    image: postgres
+`,
+      problems: []string{
+        "service 'api': dependency condition 'service_ready' is not valid",
+        "service 'api': depends on healthy 'db', but it has no healthcheck",
+        "service 'api': depends on undefined service 'queue'",
+      },
+    },
+    {
+      name: "undefined networks in short and long form",
+      data: "services:\n  api:\n    image: api\n    networks:\n      - backend\n  worker:\n    image: worker\n    networks:\n      frontend:\n        aliases:\n          - worker\n",
+      problems: []string{"service 'api': network 'backend' is not defined", "service 'worker': network 'frontend' is not defined"},
+    },
+    {
+      name: "undefined named volume",
+      data: "services:\n  db:\n    image: postgres\n    volumes:\n      - pg_data:/var/lib/postgresql/data\n      - ./init:/docker-entrypoint-initdb.d\n",
+      problems: []string{"service 'db': volume 'pg_data' is not defined"},
+    },
+    {
+      name: "healthcheck without test",
+      data: "services:\n  db:\n    image: postgres\n    healthcheck:\n      interval: 10s\n",
+      problems: []string{"service 'db': healthcheck test should be set"},
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      dockerComposeData, err := Parse(test.data)
+      if err != nil { t.Fatal(err) }
+
+      err = Validate(dockerComposeData)
+      if len(test.problems) == 0 {
+        if err != nil {
+          t.Errorf("unexpected error: %s", err)
+        }
+        return
+      }
+
+      validationErr, ok := err.(*ValidationError)
+      if !ok {
+        t.Fatalf("expected *ValidationError, got %v", err)
+      }
+      if !reflect.DeepEqual(validationErr.Problems, test.problems) {
+        t.Errorf("expected problems:\n%v\ngot:\n%v", test.problems, validationErr.Problems)
+      }
+    })
+  }
+}
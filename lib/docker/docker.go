@@ -0,0 +1,239 @@
+package docker
+
+import (
+  "bufio"
+  "context"
+  "encoding/binary"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net"
+  "net/http"
+  "net/url"
+  "os"
+  "sort"
+  "strings"
+  "time"
+)
+
+/* Labels docker compose sets on containers of project */
+const PROJECT_LABEL = "com.docker.compose.project"
+const SERVICE_LABEL = "com.docker.compose.service"
+
+const DEFAULT_HOST = "unix:///var/run/docker.sock"
+
+/**
+* Docker client: devlab talks to docker through this interface, so it could be replaced by fake
+*/
+type Client interface {
+  /* returns containers of docker compose project (stopped ones too) */
+  Containers(project string) ([]Container, error)
+  /* returns log stream of container, use ReadLogs to read it */
+  Logs(container Container, options LogsOptions) (io.ReadCloser, error)
+}
+
+type LogsOptions struct {
+  /* stream is kept open and new lines are sent while container is running */
+  Follow bool
+  /* only lines written after (zero time means all lines) */
+  Since time.Time
+}
+
+type Container struct {
+  Id string `json:"id"`
+  Name string `json:"name"`
+  Service string `json:"service"`
+  Image string `json:"image"`
+  /* created, running, restarting, exited, ... */
+  State string `json:"state"`
+  StartedAt time.Time `json:"startedAt"`
+  RestartCount int `json:"restartCount"`
+  /* starting, healthy, unhealthy or "" if container has no healthcheck */
+  Health string `json:"health"`
+  Ports []Port `json:"ports"`
+  /* output of container with tty is not multiplexed */
+  Tty bool `json:"-"`
+}
+
+type Port struct {
+  Ip string `json:"ip,omitempty"`
+  PrivatePort int `json:"privatePort"`
+  PublicPort int `json:"publicPort,omitempty"`
+  Type string `json:"type"`
+}
+
+/**
+* Client of docker Engine API (https://docs.docker.com/engine/api/) over unix socket or tcp (DOCKER_HOST)
+*/
+type EngineClient struct {
+  http *http.Client
+  baseUrl string
+}
+
+/**
+* Returns client of docker engine set in DOCKER_HOST (local unix socket by default)
+*/
+func NewClient() (*EngineClient, error) {
+  host := os.Getenv("DOCKER_HOST")
+  if host == "" {
+    host = DEFAULT_HOST
+  }
+
+  hostUrl, err := url.Parse(host)
+  if err != nil {
+    return nil, fmt.Errorf("DOCKER_HOST '%s' is not valid: %s", host, err)
+  }
+
+  switch hostUrl.Scheme {
+  case "unix":
+    socketPath := hostUrl.Path
+    transport := &http.Transport{DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+      var dialer net.Dialer
+      return dialer.DialContext(ctx, "unix", socketPath)
+    }}
+    return &EngineClient{&http.Client{Transport: transport}, "http://docker"}, nil
+  case "tcp", "http":
+    return &EngineClient{&http.Client{}, "http://" + hostUrl.Host}, nil
+  }
+
+  return nil, fmt.Errorf("DOCKER_HOST '%s' is not supported, use unix:// or tcp:// without tls", host)
+}
+
+func (client *EngineClient) Containers(project string) (containers []Container, err error) {
+  filters, _ := json.Marshal(map[string][]string{"label": {PROJECT_LABEL + "=" + project}})
+
+  var list []struct {
+    Id string
+    Names []string
+    Image string
+    Labels map[string]string
+    State string
+    Ports []struct {
+      IP string
+      PrivatePort int
+      PublicPort int
+      Type string
+    }
+  }
+  if err = client.get("/containers/json?all=1&filters=" + url.QueryEscape(string(filters)), &list); err != nil { return }
+
+  for _, item := range list {
+    container := Container{
+      Id: item.Id,
+      Service: item.Labels[SERVICE_LABEL],
+      Image: item.Image,
+      State: item.State}
+    if len(item.Names) > 0 {
+      container.Name = strings.TrimPrefix(item.Names[0], "/")
+    }
+    for _, port := range item.Ports {
+      container.Ports = append(container.Ports, Port{port.IP, port.PrivatePort, port.PublicPort, port.Type})
+    }
+
+    var details struct {
+      RestartCount int
+      Config struct {
+        Tty bool
+      }
+      State struct {
+        StartedAt time.Time
+        Health *struct {
+          Status string
+        }
+      }
+    }
+    if err = client.get("/containers/" + item.Id + "/json", &details); err != nil { return }
+
+    container.RestartCount = details.RestartCount
+    container.Tty = details.Config.Tty
+    container.StartedAt = details.State.StartedAt
+    if details.State.Health != nil {
+      container.Health = details.State.Health.Status
+    }
+
+    containers = append(containers, container)
+  }
+
+  sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+  return
+}
+
+/**
+* Lines of log have timestamps (RFC3339 with nanoseconds) followed by space
+*/
+func (client *EngineClient) Logs(container Container, options LogsOptions) (io.ReadCloser, error) {
+  query := url.Values{"stdout": {"1"}, "stderr": {"1"}, "timestamps": {"1"}}
+  if options.Follow {
+    query.Set("follow", "1")
+  }
+  if !options.Since.IsZero() {
+    query.Set("since", fmt.Sprintf("%d.%09d", options.Since.Unix(), options.Since.Nanosecond()))
+  }
+
+  return client.request("/containers/" + container.Id + "/logs?" + query.Encode())
+}
+
+/**
+* Reads log stream of container line by line: output of container without tty is multiplexed in frames
+* (8 bytes header: stream type, 3 zero bytes, big endian size of frame)
+*/
+func ReadLogs(stream io.Reader, tty bool, handle func(line string)) error {
+  reader := stream
+  if !tty {
+    pipeReader, pipeWriter := io.Pipe()
+    go func() {
+      header := make([]byte, 8)
+      for {
+        if _, err := io.ReadFull(stream, header); err != nil {
+          pipeWriter.CloseWithError(err)
+          return
+        }
+        if _, err := io.CopyN(pipeWriter, stream, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+          pipeWriter.CloseWithError(err)
+          return
+        }
+      }
+    }()
+    reader = pipeReader
+  }
+
+  scanner := bufio.NewScanner(reader)
+  scanner.Buffer(make([]byte, 64 * 1024), 1024 * 1024)
+  for scanner.Scan() {
+    handle(scanner.Text())
+  }
+
+  if err := scanner.Err(); err != nil && err != io.EOF {
+    return err
+  }
+  return nil
+}
+
+/**
+* Requests Engine API and decodes json response
+*/
+func (client *EngineClient) get(path string, result interface{}) error {
+  body, err := client.request(path)
+  if err != nil { return err }
+  defer body.Close()
+
+  return json.NewDecoder(body).Decode(result)
+}
+
+/**
+* Requests Engine API, returns body of successful response
+*/
+func (client *EngineClient) request(path string) (io.ReadCloser, error) {
+  response, err := client.http.Get(client.baseUrl + path)
+  if err != nil {
+    return nil, fmt.Errorf("docker is not available: %s", err)
+  }
+
+  if response.StatusCode >= 400 {
+    defer response.Body.Close()
+    message, _ := io.ReadAll(response.Body)
+    return nil, fmt.Errorf("docker responded to %s with status %d: %s", path, response.StatusCode, strings.TrimSpace(string(message)))
+  }
+
+  return response.Body, nil
+}
@@ -0,0 +1,316 @@
+package env
+
+import (
+  "fmt"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/files"
+  "devlab/lib/secrets"
+  "devlab/lib/state"
+)
+
+/* Context level .env used by docker compose for interpolation of ${VAR} in compose files */
+const CONTEXT_ENV_FILE = ".env"
+
+/* Folder of context with generated per-service files (${BUILD_DIR}) */
+const BUILD_DIR = "build"
+
+/* Templates of per-service .env in service repository (in order of priority) */
+var serviceTemplates = []string{"devlab.env.template", ".env.template"}
+
+/* Folder of library with templates of per-service .env (<service>.env.template) */
+const LIBRARY_TEMPLATES_DIR = "env"
+
+/* Generated files of context with resolved secrets must not be committed */
+const CONTEXT_GITIGNORE = ".gitignore"
+var contextIgnoredFiles = []string{CONTEXT_ENV_FILE, BUILD_DIR + "/", "*" + files.BACKUP_SUFFIX}
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+/* Value of .env which is written without quotes */
+var plainValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=-]*$`)
+
+/**
+* Returns variables of context: paths and prefixes used in compose files, settings of 'context' section
+* (<GROUP>_<KEY>, e.g. TASK_NAME), parameters of system services (<COMPONENT>_<PARAM>, e.g. POSTGRES_PASSWORD)
+* and hosts and ports of services (<SERVICE>_HOST, <SERVICE>_PORT inside docker network and <SERVICE>_HOST_PORT
+* allocated on host). Secret references (secret://<name>) in settings are resolved
+*/
+func ContextVariables(contextDir string, contextName string, config map[string]string, context map[string]map[string]map[string]string, contextState *state.State, parameters map[string]string, dockerComposeFiles []*DockerComposeFileBuilder.DockerComposeFile) (variables map[string]string, err error) {
+  absoluteContextDir, err := filepath.Abs(contextDir)
+  if err != nil { return }
+
+  variables = make(map[string]string)
+
+  for group, groupSettings := range context["context"] {
+    for key, value := range groupSettings {
+      if variables[Name(group + "_" + key)], err = resolve(group + "." + key, value); err != nil { return }
+    }
+  }
+
+  /* settings of context section are overridden by variables derived from them (e.g. DOCKER_NETWORK has default) */
+  for name, value := range map[string]string{
+    "CONTEXT_NAME": contextName,
+    "COMPOSE_PROJECT_NAME": "devlab-" + contextName,
+    "IMAGES_PREFIX": config["images-prefix"],
+    "DOCKER_REGISTRY_HOST": config["docker-registry-host"],
+    "DOCKER_IMAGES_PUSH_PREFIX": config["docker-images-push-prefix"],
+    "BUILD_DIR": filepath.Join(absoluteContextDir, BUILD_DIR),
+    "DEVENV_ROOT_DIR": filepath.Join(absoluteContextDir, "services"),
+    "DOCKER_NETWORK": DockerComposeFileBuilder.ContextNetwork(contextName, context)} {
+    variables[name] = value
+  }
+
+  for name, value := range parameters {
+    if variables[name], err = resolve(name, value); err != nil { return }
+  }
+
+  for _, dockerComposeData := range dockerComposeFiles {
+    for serviceName, service := range dockerComposeData.Services {
+      name := Name(serviceName)
+      variables[name + "_HOST"] = serviceName
+
+      if len(service.Ports) == 0 { continue }
+
+      parts := strings.Split(service.Ports[0], ":")
+      containerPort, _, _ := strings.Cut(parts[len(parts) - 1], "/")
+      variables[name + "_PORT"] = containerPort
+      if hostPort, ok := contextState.Ports[serviceName][parts[len(parts) - 1]]; ok {
+        variables[name + "_HOST_PORT"] = strconv.Itoa(hostPort)
+      }
+    }
+  }
+
+  return
+}
+
+/**
+* Generates context level .env and per-service .env files (${BUILD_DIR}/<service>/.env) of application services.
+* Per-service .env is rendered from template found in service repository (devlab.env.template or .env.template)
+* or in library (env/<service>.env.template), service without template gets empty .env
+*/
+func Generate(contextDir string, libraryPath string, variables map[string]string, applicationServices []string, force bool) (err error) {
+  if err = writeGitignore(contextDir); err != nil { return }
+
+  err = files.WriteGeneratedSecretFile(contextDir + "/" + CONTEXT_ENV_FILE, Format(variables), force)
+  if err != nil { return }
+
+  for _, serviceName := range applicationServices {
+    content, err := RenderService(contextDir, libraryPath, serviceName, variables)
+    if err != nil { return err }
+
+    serviceBuildDir := contextDir + "/" + BUILD_DIR + "/" + serviceName
+    if err = files.CreateDir(serviceBuildDir); err != nil { return err }
+
+    err = files.WriteGeneratedSecretFile(serviceBuildDir + "/.env", content, force)
+    if err != nil { return err }
+  }
+
+  return
+}
+
+/**
+* Renders per-service .env from its template ("" if service has no template)
+*/
+func RenderService(contextDir string, libraryPath string, serviceName string, variables map[string]string) (string, error) {
+  templatePath := FindTemplate(contextDir, libraryPath, serviceName)
+  if templatePath == "" {
+    return "", nil
+  }
+
+  template, err := files.ReadTextFile(templatePath)
+  if err != nil { return "", err }
+
+  serviceVariables := make(map[string]string)
+  for name, value := range variables {
+    serviceVariables[name] = value
+  }
+  serviceVariables["SERVICE_NAME"] = serviceName
+
+  content, err := Expand(template, serviceVariables)
+  if err != nil {
+    return "", fmt.Errorf("%s: %s", templatePath, err)
+  }
+
+  return content, nil
+}
+
+/**
+* Creates .gitignore of context with generated files or appends missing ones to existing .gitignore
+*/
+func writeGitignore(contextDir string) error {
+  path := contextDir + "/" + CONTEXT_GITIGNORE
+  data := ""
+  if isExists, _ := files.IsExists(path); isExists {
+    content, err := files.ReadTextFile(path)
+    if err != nil { return err }
+    data = content
+  }
+
+  isIgnored := make(map[string]bool)
+  for _, line := range strings.Split(data, "\n") {
+    line = strings.TrimSpace(line)
+    isIgnored[line], isIgnored[strings.TrimPrefix(line, "/")] = true, true
+  }
+
+  var missing []string
+  for _, name := range contextIgnoredFiles {
+    if !isIgnored[name] {
+      missing = append(missing, name)
+    }
+  }
+  if len(missing) == 0 { return nil }
+
+  if data != "" && !strings.HasSuffix(data, "\n") {
+    data += "\n"
+  }
+  return files.WriteFileAtomic(path, data + strings.Join(missing, "\n") + "\n")
+}
+
+/**
+* Resolves secret reference in settings value
+*/
+func resolve(key string, value string) (string, error) {
+  resolved, err := secrets.Resolve(value)
+  if err != nil {
+    return "", fmt.Errorf("settings '%s': %s", key, err)
+  }
+
+  return resolved, nil
+}
+
+/**
+* Returns path to template of per-service .env ("" if there is no template)
+*/
+func FindTemplate(contextDir string, libraryPath string, serviceName string) string {
+  var candidates []string
+  for _, template := range serviceTemplates {
+    candidates = append(candidates, contextDir + "/services/" + serviceName + "/" + template)
+  }
+  candidates = append(candidates, libraryPath + "/" + LIBRARY_TEMPLATES_DIR + "/" + serviceName + ".env.template")
+
+  for _, candidate := range candidates {
+    if isExists, _ := files.IsExists(candidate); isExists {
+      return candidate
+    }
+  }
+
+  return ""
+}
+
+/**
+* Replaces ${VAR} and ${VAR:-default} in template, returns error listing undefined variables without defaults
+*/
+func Expand(template string, variables map[string]string) (string, error) {
+  var undefined []string
+
+  result := variablePattern.ReplaceAllStringFunc(template, func(match string) string {
+    groups := variablePattern.FindStringSubmatch(match)
+    if value, ok := variables[groups[1]]; ok && (value != "" || groups[2] == "") {
+      return value
+    }
+    if groups[2] != "" {
+      return groups[3]
+    }
+
+    undefined = append(undefined, groups[1])
+    return match
+  })
+
+  if len(undefined) > 0 {
+    return result, fmt.Errorf("undefined variables: %s", strings.Join(undefined, ", "))
+  }
+
+  return result, nil
+}
+
+/**
+* Formats variables as .env file (sorted by name)
+*/
+func Format(variables map[string]string) string {
+  names := make([]string, 0, len(variables))
+  for name := range variables {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  var text strings.Builder
+  for _, name := range names {
+    text.WriteString(name + "=" + Quote(variables[name]) + "\n")
+  }
+
+  return text.String()
+}
+
+/**
+* Quotes value of .env, so docker compose reads it as is: value with special characters is single quoted
+* (it is not interpolated), value with single quote or new line is double quoted with escapes
+*/
+func Quote(value string) string {
+  if plainValuePattern.MatchString(value) { return value }
+
+  if !strings.ContainsAny(value, "'\n") {
+    return "'" + value + "'"
+  }
+
+  return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, `$`, `\$`).Replace(value) + `"`
+}
+
+/**
+* Parses .env file (comments and empty lines are skipped), quoted values are unquoted
+*/
+func Parse(content string) map[string]string {
+  variables := make(map[string]string)
+  for _, line := range strings.Split(content, "\n") {
+    line = strings.TrimSpace(line)
+    if line == "" || strings.HasPrefix(line, "#") { continue }
+
+    name, value, _ := strings.Cut(line, "=")
+    variables[strings.TrimSpace(name)] = unquote(strings.TrimSpace(value))
+  }
+
+  return variables
+}
+
+/**
+* Reverses Quote
+*/
+func unquote(value string) string {
+  if len(value) < 2 { return value }
+
+  switch {
+  case value[0] == '\'' && value[len(value) - 1] == '\'':
+    return value[1:len(value) - 1]
+  case value[0] == '"' && value[len(value) - 1] == '"':
+    var result strings.Builder
+    isEscaped := false
+    for _, char := range value[1:len(value) - 1] {
+      switch {
+      case isEscaped && char == 'n':
+        result.WriteRune('\n')
+      case isEscaped:
+        result.WriteRune(char)
+      case char == '\\':
+        isEscaped = true
+        continue
+      default:
+        result.WriteRune(char)
+      }
+      isEscaped = false
+    }
+    return result.String()
+  }
+
+  return value
+}
+
+/**
+* Returns name of variable for key ('dlp-service-config' => 'DLP_SERVICE_CONFIG')
+*/
+func Name(key string) string {
+  return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+}
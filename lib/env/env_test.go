@@ -0,0 +1,139 @@
+package env
+
+import (
+  "os"
+  "path/filepath"
+  "reflect"
+  "testing"
+)
+
+func TestQuote(t *testing.T) {
+  tests := []struct {
+    value string
+    quoted string
+  }{
+    {value: "", quoted: ""},
+    {value: "postgres", quoted: "postgres"},
+    {value: "http://user@host:8080/path,other+1=2", quoted: "http://user@host:8080/path,other+1=2"},
+    {value: "two words", quoted: "'two words'"},
+    {value: "pa$$word", quoted: "'pa$$word'"},
+    {value: "${HOME}", quoted: "'${HOME}'"},
+    {value: `back\slash "quoted" #hash`, quoted: `'back\slash "quoted" #hash'`},
+    {value: "it's", quoted: `"it's"`},
+    {value: "it's ${HOME}", quoted: `"it's \${HOME}"`},
+    {value: "first\nsecond", quoted: `"first\nsecond"`},
+    {value: `it's "C:\dir"`, quoted: `"it's \"C:\\dir\""`},
+  }
+
+  for _, test := range tests {
+    t.Run(test.value, func(t *testing.T) {
+      quoted := Quote(test.value)
+      if quoted != test.quoted {
+        t.Errorf("expected %s, got %s", test.quoted, quoted)
+      }
+
+      /* .env written by Format is parsed back to the same value */
+      variables := Parse(Format(map[string]string{"VALUE": test.value}))
+      if variables["VALUE"] != test.value {
+        t.Errorf("expected to parse %q, got %q", test.value, variables["VALUE"])
+      }
+    })
+  }
+}
+
+func TestParse(t *testing.T) {
+  content := "# generated by devlab\n\nPLAIN=value\n  SPACED = 'single quoted'  \nDOUBLE=\"a\\\"b\"\nEMPTY=\nNO_VALUE\nURL=http://host/?a=b\n"
+  expected := map[string]string{"PLAIN": "value", "SPACED": "single quoted", "DOUBLE": `a"b`, "EMPTY": "", "NO_VALUE": "", "URL": "http://host/?a=b"}
+
+  if variables := Parse(content); !reflect.DeepEqual(variables, expected) {
+    t.Errorf("expected %v, got %v", expected, variables)
+  }
+}
+
+func TestExpand(t *testing.T) {
+  variables := map[string]string{"HOST": "postgres", "PORT": "5432", "EMPTY": ""}
+
+  tests := []struct {
+    name string
+    template string
+    expected string
+    isError bool
+  }{
+    {name: "variables", template: "postgres://${HOST}:${PORT}/db", expected: "postgres://postgres:5432/db"},
+    {name: "default of undefined variable", template: "${USER:-admin}@${HOST}", expected: "admin@postgres"},
+    {name: "default of empty variable", template: "[${EMPTY:-none}]", expected: "[none]"},
+    {name: "default of defined variable", template: "${PORT:-80}", expected: "5432"},
+    {name: "empty default", template: "[${USER:-}]", expected: "[]"},
+    {name: "empty variable without default", template: "[${EMPTY}]", expected: "[]"},
+    {name: "not variables", template: "$HOST ${1X} $${", expected: "$HOST ${1X} $${"},
+    {name: "undefined variable", template: "${HOST}:${MISSING}", expected: "postgres:${MISSING}", isError: true},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      result, err := Expand(test.template, variables)
+      if test.isError != (err != nil) {
+        t.Errorf("expected error %t, got %v", test.isError, err)
+      }
+      if result != test.expected {
+        t.Errorf("expected '%s', got '%s'", test.expected, result)
+      }
+    })
+  }
+}
+
+func TestWriteGitignore(t *testing.T) {
+  tests := []struct {
+    name string
+    /* content of existing .gitignore (there is no .gitignore if nil) */
+    existing *string
+    expected string
+  }{
+    {
+      name: "new gitignore",
+      expected: ".env\nbuild/\n*.bak\n",
+    },
+    {
+      name: "existing gitignore without generated files",
+      existing: stringPointer("node_modules/\n# local notes\nnotes.md"),
+      expected: "node_modules/\n# local notes\nnotes.md\n.env\nbuild/\n*.bak\n",
+    },
+    {
+      name: "existing gitignore with some generated files",
+      existing: stringPointer("/.env\nservices/\n"),
+      expected: "/.env\nservices/\nbuild/\n*.bak\n",
+    },
+    {
+      name: "existing gitignore with all generated files",
+      existing: stringPointer("*.bak\nbuild/\n.env\n"),
+      expected: "*.bak\nbuild/\n.env\n",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      contextDir := t.TempDir()
+      path := filepath.Join(contextDir, CONTEXT_GITIGNORE)
+      if test.existing != nil {
+        if err := os.WriteFile(path, []byte(*test.existing), 0644); err != nil { t.Fatal(err) }
+      }
+
+      /* the second run doesn't change .gitignore */
+      for run := 0; run < 2; run++ {
+        if err := writeGitignore(contextDir); err != nil {
+          t.Fatalf("unexpected error: %s", err)
+        }
+
+        data, err := os.ReadFile(path)
+        if err != nil { t.Fatal(err) }
+        if string(data) != test.expected {
+          t.Errorf("run %d: expected:\n%s\ngot:\n%s", run, test.expected, data)
+        }
+      }
+    })
+  }
+}
+
+func stringPointer(value string) *string {
+  return &value
+}
@@ -1,7 +1,13 @@
 package exec
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"runtime"
+	"time"
 )
 
 /**
@@ -14,4 +20,81 @@ func GitCommand(serviceDir string, command string)  (result string, err error) {
 		"( cd " + serviceDir + " && " + command + ")" ).Output()
 	
 	return string(out), err
-}
\ No newline at end of file
+}
+
+/**
+*  Executes shell command in current folder
+*/
+func Command(command string) (result string, err error) {
+  out, err := exec.Command("sh", "-c", command).Output()
+
+  return string(out), err
+}
+
+/**
+*  Executes shell command in current folder, command is killed after timeout
+*/
+func CommandWithTimeout(command string, timeout time.Duration) (result string, err error) {
+  ctx, cancel := context.WithTimeout(context.Background(), timeout)
+  defer cancel()
+
+  cmd := exec.CommandContext(ctx, "sh", "-c", command)
+  /* processes started by shell could keep output open after shell is killed */
+  cmd.WaitDelay = time.Second
+  out, err := cmd.Output()
+  if ctx.Err() == context.DeadlineExceeded {
+    err = fmt.Errorf("command is timed out after %s", timeout)
+  }
+
+  return string(out), err
+}
+
+/**
+*  Executes shell command attached to the terminal (for interactive commands like 'docker login')
+*/
+func Interactive(command string) error {
+  cmd := exec.Command("sh", "-c", command)
+  cmd.Stdin = os.Stdin
+  cmd.Stdout = os.Stdout
+  cmd.Stderr = os.Stderr
+
+  return cmd.Run()
+}
+
+/**
+*  Executes shell command with stdin and stdout connected to reader and writer (stderr is attached to the terminal)
+*/
+func Stream(command string, stdin io.Reader, stdout io.Writer) error {
+  cmd := exec.Command("sh", "-c", command)
+  cmd.Stdin = stdin
+  cmd.Stdout = stdout
+  cmd.Stderr = os.Stderr
+
+  return cmd.Run()
+}
+
+/**
+*  Returns true if file is attached to a terminal (e.g. stdin is not piped)
+*/
+func IsTerminal(file *os.File) bool {
+  info, err := file.Stat()
+
+  return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+/**
+*  Opens url in default browser (url is passed as argument, so it is not interpreted by shell)
+*/
+func Browser(url string) error {
+  cmd := exec.Command("xdg-open", url)
+  switch runtime.GOOS {
+  case "darwin":
+    cmd = exec.Command("open", url)
+  case "windows":
+    cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+  }
+  cmd.Stdout = os.Stdout
+  cmd.Stderr = os.Stderr
+
+  return cmd.Run()
+}
@@ -1,13 +1,14 @@
 package files
 
 import (
+  "crypto/sha256"
+  "fmt"
   "io"
   "os"
   "path/filepath"
+  "strings"
   "devlab/lib/errors"
   "devlab/lib/yml"
-  "devlab/lib/logger"
-  "reflect"
 )
 /**
 */
@@ -32,12 +33,12 @@ func ReadTextFile(path string) (resultString string, err error) {
      
   data := make([]byte, 64)     
   for {
-    n, err := file.Read(data)
-    if err == io.EOF { break }
+    n, readErr := file.Read(data)
     resultString += string(data[:n])
+    if readErr == io.EOF { break }
+    if readErr != nil { return "", readErr }
   }
   
-  err = nil
   return
 }
 
@@ -85,51 +86,210 @@ func Copy(src, dst string) error {
   return out.Close()
 }
 
-/*
+const MAIN_CONFIG_FILE = ".config"
+
+/**
+* Error returned when there is no project .config in the folder and its parents
 */
-func ReadMainConfig() (config map[string]string, err error) {
-  _, err =  IsExists("/.config")
-  if errors.CheckAndReturnIfError(err) { return make(map[string]string), err }
+type MainConfigNotFoundError struct {
+  SearchedPaths []string
+}
 
-  pathToConfig, err := AbsolutePath(".config")
-  
-  configData, err := ReadTextFile(pathToConfig)
-  if errors.CheckAndReturnIfError(err) { return  make(map[string]string), err }
+func (e *MainConfigNotFoundError) Error() string {
+  return "no project config found, searched:\n  " + strings.Join(e.SearchedPaths, "\n  ") +
+    "\nRun 'devlab setup' in devlab folder to create " + MAIN_CONFIG_FILE
+}
+
+/**
+* Finds project .config walking up from startDir
+*/
+func FindMainConfig(startDir string) (path string, err error) {
+  path, searchedPaths, err := FindUp(startDir, MAIN_CONFIG_FILE)
+  if err == nil && path == "" {
+    return "", &MainConfigNotFoundError{searchedPaths}
+  }
+
+  return
+}
+
+/**
+* Walks up from startDir until folder containing one of names (checked in order) is found, returns path to found
+* file ("" if there is no such folder) and paths which were checked
+*/
+func FindUp(startDir string, names ...string) (path string, searchedPaths []string, err error) {
+  dir, err := AbsolutePath(startDir)
+  if err != nil { return }
+
+  for {
+    for _, name := range names {
+      path = filepath.Join(dir, name)
+      searchedPaths = append(searchedPaths, path)
+
+      isExists, err := IsExists(path)
+      if err != nil { return "", searchedPaths, err }
+      if isExists { return path, searchedPaths, nil }
+    }
+
+    parent := filepath.Dir(dir)
+    if parent == dir {
+      return "", searchedPaths, nil
+    }
+    dir = parent
+  }
+}
+
+/**
+* Reads project .config found from startDir, returns config and path to it
+*/
+func ReadMainConfigFrom(startDir string) (config map[string]string, path string, err error) {
+  path, err = FindMainConfig(startDir)
+  if err != nil { return }
+
+  configData, err := ReadTextFile(path)
+  if errors.CheckAndReturnIfError(err) { return }
 
   config, err = yml.ParseOneLevelYAML(configData)
-  if errors.CheckAndReturnIfError(err) { return  make(map[string]string), err }
+  if err != nil {
+    return nil, path, fmt.Errorf("%s: %s", path, err)
+  }
+  if config == nil {
+    config = make(map[string]string)
+  }
 
   return
 }
 
+/**
+* Reads project .config found from current working directory
+*/
+func ReadMainConfig() (config map[string]string, err error) {
+  config, _, err = ReadMainConfigFrom(".")
+  return
+}
+
+/**
+* Reads settings.yml of context, see yml.ParseSettingsYAML for its forms
+*/
 func ReadContextConfig(relativePathToContextConfigFile string) (context map[string]map[string]map[string]string, err error) {
 	contextData, err := ReadTextFile(relativePathToContextConfigFile)
 	if errors.CheckAndReturnIfError(err) { return  make(map[string]map[string]map[string]string), err }
   
-	context, err = yml.ParseThreeLevelYAML(contextData)
+	context, err = yml.ParseSettingsYAML(contextData)
 	if errors.CheckAndReturnIfError(err) { return  make(map[string]map[string]map[string]string), err }
 
 	return
 }
 
 /**
-* Writes string to the of file
+* Writes data to file atomically: data is written to temporary file in the same folder
+* and then renamed to target file, so the target file is never left half-written
 */
-func WriteAppendFile(filenamePath string, text string) (result int, err error) { 
+func WriteFileAtomic(filenamePath string, data string) (err error) {
+  return WriteFileAtomicWithMode(filenamePath, data, 0644)
+}
+
+/**
+* Writes data to file atomically with permissions of mode (temporary file is created readable by owner only,
+* so file with secrets is never readable by others)
+*/
+func WriteFileAtomicWithMode(filenamePath string, data string, mode os.FileMode) (err error) {
   absoluteFilenamePath, err := AbsolutePath(filenamePath)
-  isFileExists, _ :=  IsExists(filenamePath)
-  logger.Debug(" %s ", absoluteFilenamePath)
+  if errors.CheckAndReturnIfError(err) { return }
 
-  if !isFileExists {
-    logger.Debug("text:  %s ", text)
-    file, _ := os.Create(absoluteFilenamePath)
-    defer file.Close() 
-  }
+  tmpFile, err := os.CreateTemp(filepath.Dir(absoluteFilenamePath), "." + filepath.Base(absoluteFilenamePath) + ".tmp-*")
+  if errors.CheckAndReturnIfError(err) { return }
+  defer os.Remove(tmpFile.Name())
 
-  file, _ := os.OpenFile(absoluteFilenamePath, os.O_WRONLY|os.O_APPEND, 0644)
-  if err != nil {
+  if _, err = tmpFile.WriteString(data); err != nil {
+    tmpFile.Close()
     return
   }
+  if err = tmpFile.Close(); err != nil { return }
+  if err = os.Chmod(tmpFile.Name(), mode); err != nil { return }
+
+  return os.Rename(tmpFile.Name(), absoluteFilenamePath)
+}
+
+const GENERATED_HEADER_PREFIX = "# Generated by devlab, do not edit. checksum: "
+const BACKUP_SUFFIX = ".bak"
+
+/**
+* Error returned when generated file has been edited after devlab generated it
+*/
+type GeneratedFileChangedError struct {
+  Path string
+}
+
+func (e *GeneratedFileChangedError) Error() string {
+  return "file " + e.Path + " changed since devlab generated it, " +
+    "use --force to overwrite it (previous version will be saved to " + e.Path + BACKUP_SUFFIX + ")"
+}
+
+/**
+* Writes generated artifact atomically with header containing checksum of its content.
+* Existing file is backed up, and it is not overwritten (without force) if it was edited by hand
+*/
+func WriteGeneratedFile(filenamePath string, content string, force bool) error {
+  return writeGeneratedFile(filenamePath, content, force, 0644)
+}
+
+/**
+* Writes generated artifact with secrets (e.g. .env with resolved secret references) readable by owner only,
+* see WriteGeneratedFile
+*/
+func WriteGeneratedSecretFile(filenamePath string, content string, force bool) error {
+  return writeGeneratedFile(filenamePath, content, force, 0600)
+}
+
+func writeGeneratedFile(filenamePath string, content string, force bool, mode os.FileMode) (err error) {
+  data := GENERATED_HEADER_PREFIX + checksum(content) + "\n" + content
+
+  isExists, err := IsExists(filenamePath)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  if isExists {
+    previousData, err := ReadTextFile(filenamePath)
+    if errors.CheckAndReturnIfError(err) { return err }
+
+    /* file written by earlier version could have wider permissions */
+    if previousData == data { return os.Chmod(filenamePath, mode) }
+
+    if !force && IsGeneratedFileChanged(previousData) {
+      return &GeneratedFileChangedError{filenamePath}
+    }
+
+    err = WriteFileAtomicWithMode(filenamePath + BACKUP_SUFFIX, previousData, mode)
+    if errors.CheckAndReturnIfError(err) { return err }
+  }
+
+  return WriteFileAtomicWithMode(filenamePath, data, mode)
+}
+
+/**
+* Checks if generated file data does not match checksum in its header (or has no header at all)
+*/
+func IsGeneratedFileChanged(data string) bool {
+  header, content, _ := strings.Cut(data, "\n")
+  if !strings.HasPrefix(header, GENERATED_HEADER_PREFIX) {
+    return true
+  }
+
+  return strings.TrimPrefix(header, GENERATED_HEADER_PREFIX) != checksum(content)
+}
+
+func checksum(content string) string {
+  return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(content)))
+}
+
+/**
+* Writes string to the of file
+*/
+func WriteAppendFile(filenamePath string, text string) (result int, err error) { 
+  absoluteFilenamePath, err := AbsolutePath(filenamePath)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  file, err := os.OpenFile(absoluteFilenamePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+  if errors.CheckAndReturnIfError(err) { return }
   defer file.Close()
 
   result, err = file.WriteString(text + "\n")
@@ -145,37 +305,33 @@ func WriteAppendFileWithIndent(filenamePath string, text string, indent int) (re
 }
 
 /**
-* Writes tree stucture data (with 3 levels) to yaml file
+* Writes data (maps, slices, structs with `yaml` tags, yml.Tree) to yaml file atomically
 */
 func WriteYaml(filenamePath string, data interface{}) (err error) {   
-  return WriteYamlBranch(filenamePath, data, 0)
+  yamlData, err := yml.Marshal(data)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  return WriteFileAtomic(filenamePath, string(yamlData))
 }
 
 /**
-* Writes one branch of tree stucture data to yaml file
+* Sets value at path of keys in yaml file (e.g. user's settings.yml) keeping its comments and order of keys
 */
-func WriteYamlBranch(filenamePath string, data interface{}, depth int) (err error) { 
-  if data, ok := data.(map[string]map[string]map[string]string); ok {
-    for key, value := range data {
-      _, err = WriteAppendFileWithIndent(filenamePath, key + ": ", 2*depth)
-      err = WriteYamlBranch(filenamePath, value, depth + 1)
-    }
-  }
+func UpdateYaml(filenamePath string, path []string, value interface{}) (err error) {
+  data, err := ReadTextFile(filenamePath)
+  if errors.CheckAndReturnIfError(err) { return }
 
-  if data, ok := data.(map[string]map[string]string); ok {
-    for key, value := range data {
-      _, err = WriteAppendFileWithIndent(filenamePath, key + ": ", 2*depth)
-      err = WriteYamlBranch(filenamePath, value, depth + 1)
-    }
-  }
+  document, err := yml.ParseDocument(data)
+  if err != nil { return fmt.Errorf("%s: %s", filenamePath, err) }
 
-  if data, ok := data.(map[string]string); ok {
-    for key, value := range data {
-      _, err = WriteAppendFileWithIndent(filenamePath, key + ": " + value, 2*depth)
-    }
+  if err = yml.SetValue(document, path, value); err != nil {
+    return fmt.Errorf("%s: %s", filenamePath, err)
   }
 
-  return
+  yamlData, err := yml.Render(document)
+  if errors.CheckAndReturnIfError(err) { return }
+
+  return WriteFileAtomic(filenamePath, string(yamlData))
 }
 
 /**
@@ -189,47 +345,3 @@ func indentInSpaces(indent int) (spacesIndent string) {
   return spacesIndent
 }
 
-
-type Pair struct {
-  key string
-  value *interface{}
-}
-
-func (p Pair) writeAppendYAMLFile(filenamePath string, depth int) {
-  v := reflect.ValueOf(*(p.value))
-  switch  v.Kind() {
-    case reflect.String:
-      WriteAppendFileWithIndent(filenamePath, p.key + ": " + v.Elem().String(), depth)
-    
-    case reflect.Slice, reflect.Array:
-      WriteAppendFileWithIndent(filenamePath, p.key + ": ", depth)
-      for i := 0; i < v.NumField(); i++ {
-        item := v.Field(i)        
-        if reflect.ValueOf(item).Kind() == reflect.String {
-          WriteAppendFileWithIndent(filenamePath, "- " + reflect.ValueOf(item).Elem().String(), depth + 2)
-        } else {
-          WriteAppendFileWithIndent(filenamePath, " - the value couldn't be printed", depth + 2) 
-        }
-      }
-        
-    case reflect.Map:
-      WriteAppendFileWithIndent(filenamePath, p.key + ": ", depth)
-      for _, key := range v.MapKeys() {
-       // (Pair{key.Elem().String(), v.MapIndex(key).Pointer()}).writeAppendYAMLFile(filenamePath, depth + 2)
-        // (Pair{key.Elem().String(), &"unknown value"}).writeAppendYAMLFile(filenamePath, depth + 2)
-        WriteAppendFileWithIndent(filenamePath, key.Elem().String() + ": unknown value", depth +2) 
-      }
-      
-    default:
-      WriteAppendFileWithIndent(filenamePath, p.key + ": the value couldn't be printed", depth)    
-  }
-}
-
-type YamlTree []Pair
-
-func (t YamlTree) WriteYaml(filenamePath string) {
-  for _, pair := range t {
-    pair.writeAppendYAMLFile(filenamePath, 0) 
-  }
-}
-
@@ -0,0 +1,183 @@
+package files
+
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+func TestReadMainConfigFrom(t *testing.T) {
+  tests := []struct {
+    name string
+    /* files of project tree (folder is created for path ending with '/') */
+    files map[string]string
+    /* folder config is searched from */
+    startDir string
+    /* folder of found config */
+    configDir string
+    config map[string]string
+    isNotFound bool
+    isError bool
+  }{
+    {
+      name: "config in current folder",
+      files: map[string]string{".config": "contexts-path: contexts\nbase-branch: main\n"},
+      startDir: ".",
+      configDir: ".",
+      config: map[string]string{"contexts-path": "contexts", "base-branch": "main"},
+    },
+    {
+      name: "config in parent folder",
+      files: map[string]string{".config": "contexts-path: contexts\n", "contexts/foo/services/": ""},
+      startDir: "contexts/foo/services",
+      configDir: ".",
+      config: map[string]string{"contexts-path": "contexts"},
+    },
+    {
+      name: "nearest config wins",
+      files: map[string]string{".config": "contexts-path: contexts\n", "nested/.config": "contexts-path: nested\n"},
+      startDir: "nested",
+      configDir: "nested",
+      config: map[string]string{"contexts-path": "nested"},
+    },
+    {
+      name: "empty config",
+      files: map[string]string{".config": ""},
+      startDir: ".",
+      configDir: ".",
+      config: map[string]string{},
+    },
+    {
+      name: "no config",
+      files: map[string]string{"contexts/foo/": ""},
+      startDir: "contexts/foo",
+      isNotFound: true,
+    },
+    {
+      name: "invalid config",
+      files: map[string]string{".config": "contexts-path: [contexts\n"},
+      startDir: ".",
+      configDir: ".",
+      isError: true,
+    },
+    {
+      name: "unreadable config",
+      files: map[string]string{".config/": ""},
+      startDir: ".",
+      configDir: ".",
+      isError: true,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      root := projectTree(t, test.files)
+      startDir := filepath.Join(root, test.startDir)
+
+      config, path, err := ReadMainConfigFrom(startDir)
+
+      if test.isNotFound {
+        notFoundErr, ok := err.(*MainConfigNotFoundError)
+        if !ok {
+          t.Fatalf("expected *MainConfigNotFoundError, got %v (config %s)", err, path)
+        }
+        if notFoundErr.SearchedPaths[0] != filepath.Join(startDir, MAIN_CONFIG_FILE) {
+          t.Errorf("search should start in %s, searched paths: %v", startDir, notFoundErr.SearchedPaths)
+        }
+        if !contains(notFoundErr.SearchedPaths, filepath.Join(root, MAIN_CONFIG_FILE)) {
+          t.Errorf("parent %s should be searched, searched paths: %v", root, notFoundErr.SearchedPaths)
+        }
+        if last := notFoundErr.SearchedPaths[len(notFoundErr.SearchedPaths) - 1]; last != filepath.Join(string(filepath.Separator), MAIN_CONFIG_FILE) {
+          t.Errorf("search should end in filesystem root, last searched path is %s", last)
+        }
+        if !strings.Contains(err.Error(), "devlab setup") {
+          t.Errorf("error should suggest to run setup: %s", err)
+        }
+        return
+      }
+
+      if test.isError {
+        if err == nil {
+          t.Fatalf("expected error, got config %v", config)
+        }
+        if _, isNotFound := err.(*MainConfigNotFoundError); isNotFound {
+          t.Fatalf("expected read error, got %s", err)
+        }
+        return
+      }
+
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+      if expectedPath := filepath.Join(root, test.configDir, MAIN_CONFIG_FILE); path != expectedPath {
+        t.Errorf("expected config %s, got %s", expectedPath, path)
+      }
+      if len(config) != len(test.config) {
+        t.Errorf("expected config %v, got %v", test.config, config)
+      }
+      for key, value := range test.config {
+        if config[key] != value {
+          t.Errorf("'%s': expected '%s', got '%s'", key, value, config[key])
+        }
+      }
+    })
+  }
+}
+
+/**
+* Settings edited by devlab keep comments and order of keys written by user
+*/
+func TestUpdateYaml(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "settings.yml")
+  settings := "# context settings\nsystem-services:\n  postgres:\n    enabled: true   # database\n  kafka:\n    enabled: false\n"
+  if err := os.WriteFile(path, []byte(settings), 0644); err != nil { t.Fatal(err) }
+
+  if err := UpdateYaml(path, []string{"system-services", "kafka", "enabled"}, true); err != nil {
+    t.Fatalf("unexpected error: %s", err)
+  }
+  if err := UpdateYaml(path, []string{"system-services", "kafka", "extensions"}, "ui,connect"); err != nil {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  data, err := os.ReadFile(path)
+  if err != nil { t.Fatal(err) }
+  expected := "# context settings\nsystem-services:\n  postgres:\n    enabled: true # database\n  kafka:\n    enabled: true\n    extensions: ui,connect\n"
+  if string(data) != expected {
+    t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+  }
+
+  if err := UpdateYaml(filepath.Join(filepath.Dir(path), "missing.yml"), []string{"key"}, "value"); err == nil {
+    t.Errorf("missing file should be an error")
+  }
+}
+
+/**
+* Creates project tree in temporary folder, skips test if there is config above temporary folder
+*/
+func projectTree(t *testing.T, tree map[string]string) string {
+  root := t.TempDir()
+  if path, _, _ := FindUp(filepath.Dir(root), MAIN_CONFIG_FILE); path != "" {
+    t.Skipf("%s is found above temporary folder", path)
+  }
+
+  for path, content := range tree {
+    fullPath := filepath.Join(root, path)
+    if strings.HasSuffix(path, "/") {
+      if err := os.MkdirAll(fullPath, 0755); err != nil { t.Fatal(err) }
+      continue
+    }
+
+    if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil { t.Fatal(err) }
+  }
+
+  return root
+}
+
+func contains(values []string, value string) bool {
+  for _, item := range values {
+    if item == value { return true }
+  }
+  return false
+}
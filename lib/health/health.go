@@ -0,0 +1,149 @@
+package health
+
+import (
+  "fmt"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+/* Interval between probes of not healthy service */
+const PROBE_INTERVAL = 2 * time.Second
+
+/**
+* Health check of service, exactly one of checks is set:
+*   tcp: <container port>            port accepts connections
+*   http: <container port>/<path>    endpoint responds with status < 400
+*   command: <shell command>         command run inside container exits with 0
+*/
+type Check struct {
+  Tcp int `yaml:"tcp,omitempty"`
+  Http string `yaml:"http,omitempty"`
+  Command string `yaml:"command,omitempty"`
+}
+
+/**
+* Returns health checks of services: checks of library components and checks set in settings.yml of context
+* as params of service (health-tcp, health-http, health-command), settings override library
+*/
+func Load(libraryChecks map[string]*Check, context map[string]map[string]map[string]string) (checks map[string]*Check, err error) {
+  checks = make(map[string]*Check)
+  for serviceName, check := range libraryChecks {
+    checks[serviceName] = check
+  }
+
+  for _, section := range []string{"system-services", "applicaton-services"} {
+    for serviceName, serviceParams := range context[section] {
+      check := &Check{Http: serviceParams["health-http"], Command: serviceParams["health-command"]}
+      if serviceParams["health-tcp"] != "" {
+        if check.Tcp, err = strconv.Atoi(serviceParams["health-tcp"]); err != nil {
+          return nil, fmt.Errorf("service '%s': health-tcp should be port number", serviceName)
+        }
+      }
+
+      if *check != (Check{}) {
+        checks[serviceName] = check
+      }
+    }
+  }
+
+  for serviceName, check := range checks {
+    if err = check.Validate(); err != nil {
+      return nil, fmt.Errorf("service '%s': %s", serviceName, err)
+    }
+  }
+
+  return
+}
+
+/**
+* Returns port and path of http check ('8500/v1/status/leader' => 8500, '/v1/status/leader')
+*/
+func (check *Check) HttpPortAndPath() (port int, path string, err error) {
+  portValue, path, _ := strings.Cut(check.Http, "/")
+  if port, err = strconv.Atoi(portValue); err != nil {
+    return 0, "", fmt.Errorf("http check '%s' should be <container port>/<path>", check.Http)
+  }
+
+  return port, "/" + path, nil
+}
+
+func (check *Check) String() string {
+  switch {
+  case check.Tcp != 0:
+    return fmt.Sprintf("tcp %d", check.Tcp)
+  case check.Http != "":
+    return "http " + check.Http
+  }
+
+  return "command '" + check.Command + "'"
+}
+
+/**
+* Checks that exactly one of checks is set and http check has port
+*/
+func (check *Check) Validate() error {
+  count := 0
+  for _, isSet := range []bool{check.Tcp != 0, check.Http != "", check.Command != ""} {
+    if isSet { count++ }
+  }
+  if count != 1 {
+    return fmt.Errorf("health check should have exactly one of tcp, http, command")
+  }
+
+  if check.Http != "" {
+    _, _, err := check.HttpPortAndPath()
+    return err
+  }
+
+  return nil
+}
+
+/**
+* Probes services until every one is healthy (probe returns nil) or timeout is reached,
+* returns last probe errors of services which are not healthy
+*/
+func Wait(serviceNames []string, timeout time.Duration, probe func(serviceName string) error) (failed map[string]error) {
+  failed = make(map[string]error)
+  deadline := time.Now().Add(timeout)
+
+  var mutex sync.Mutex
+  var waitGroup sync.WaitGroup
+  for _, serviceName := range serviceNames {
+    waitGroup.Add(1)
+    go func(serviceName string) {
+      defer waitGroup.Done()
+      for {
+        err := probe(serviceName)
+        if err == nil { return }
+
+        if time.Now().Add(PROBE_INTERVAL).After(deadline) {
+          mutex.Lock()
+          failed[serviceName] = err
+          mutex.Unlock()
+          return
+        }
+        time.Sleep(PROBE_INTERVAL)
+      }
+    }(serviceName)
+  }
+  waitGroup.Wait()
+
+  return
+}
+
+/**
+* Returns sorted names of services which have health checks
+*/
+func Services(checks map[string]*Check, serviceNames []string) (checked []string) {
+  for _, serviceName := range serviceNames {
+    if _, ok := checks[serviceName]; ok {
+      checked = append(checked, serviceName)
+    }
+  }
+  sort.Strings(checked)
+
+  return
+}
@@ -0,0 +1,208 @@
+package kafka
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "strings"
+  "devlab/lib/util"
+)
+
+/* Section of settings.yml with topics of context ('kafka: topics:' or 'kafka-topics:'): <topic>: {partitions, replication, configs: <key>=<value>,...} */
+const TOPICS_SECTION = "kafka-topics"
+
+/* Service of broker, topics are managed with kafka scripts run inside its container */
+const BROKER_SERVICE = "kafka"
+const TOPICS_COMMAND = "/opt/kafka/bin/kafka-topics.sh --bootstrap-server localhost:9092"
+const CONFIGS_COMMAND = "/opt/kafka/bin/kafka-configs.sh --bootstrap-server localhost:9092 --entity-type topics"
+
+const ACTION_NONE = "ok"
+const ACTION_CREATE = "create"
+const ACTION_ALTER = "alter"
+/* declared topic differs in a way kafka can't change (fewer partitions, other replication factor) */
+const ACTION_CONFLICT = "conflict"
+/* topic exists in broker, but is not declared in settings.yml */
+const ACTION_UNDECLARED = "undeclared"
+
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+/* Field of line of 'kafka-topics.sh --describe' ('Topic: orders', 'PartitionCount:3') */
+var describeFieldPattern = regexp.MustCompile(`(\w+):\s*(\S*)`)
+
+type Topic struct {
+  Name string
+  Partitions int
+  Replication int
+  Configs map[string]string
+}
+
+/**
+* Difference between declared and actual topic with commands which apply declared topic
+*/
+type Change struct {
+  Topic string
+  Action string
+  Details []string
+  Commands []string
+}
+
+/**
+* Returns topics declared in settings.yml of context (partitions and replication are 1 by default)
+*/
+func Declared(context map[string]map[string]map[string]string) (topics map[string]*Topic, err error) {
+  topics = make(map[string]*Topic)
+  for name, params := range context[TOPICS_SECTION] {
+    if !topicNamePattern.MatchString(name) || len(name) > 249 {
+      return nil, fmt.Errorf("kafka topic '%s': name should contain letters, digits, '.', '_' and '-'", name)
+    }
+
+    topic := &Topic{Name: name, Partitions: 1, Replication: 1, Configs: make(map[string]string)}
+    for key, target := range map[string]*int{"partitions": &topic.Partitions, "replication": &topic.Replication} {
+      if params[key] == "" { continue }
+      if *target, err = strconv.Atoi(params[key]); err != nil || *target < 1 {
+        return nil, fmt.Errorf("kafka topic '%s': %s should be positive number", name, key)
+      }
+    }
+    if topic.Configs, err = parseConfigs(params["configs"]); err != nil {
+      return nil, fmt.Errorf("kafka topic '%s': %s", name, err)
+    }
+
+    topics[name] = topic
+  }
+
+  return
+}
+
+/**
+* Parses output of 'kafka-topics.sh --describe': topic lines with partition count, replication factor and configs
+* (lines of partitions are skipped)
+*/
+func ParseDescribe(out string) (topics map[string]*Topic) {
+  topics = make(map[string]*Topic)
+  for _, line := range strings.Split(out, "\n") {
+    fields := make(map[string]string)
+    for _, match := range describeFieldPattern.FindAllStringSubmatch(line, -1) {
+      fields[match[1]] = match[2]
+    }
+    if fields["Topic"] == "" || fields["PartitionCount"] == "" { continue }
+
+    topic := &Topic{Name: fields["Topic"]}
+    topic.Partitions, _ = strconv.Atoi(fields["PartitionCount"])
+    topic.Replication, _ = strconv.Atoi(fields["ReplicationFactor"])
+    topic.Configs, _ = parseConfigs(fields["Configs"])
+    topics[topic.Name] = topic
+  }
+
+  return
+}
+
+/**
+* Returns changes of topics sorted by name: declared topics which are missing or differ from actual ones and actual
+* topics which are not declared (internal topics are skipped). Only declared configs are compared
+*/
+func Diff(declared map[string]*Topic, actual map[string]*Topic) (changes []*Change) {
+  names := make(map[string]string)
+  for name := range declared {
+    names[name] = name
+  }
+  for name := range actual {
+    if !strings.HasPrefix(name, "__") {
+      names[name] = name
+    }
+  }
+
+  for _, name := range util.SortedKeys(names) {
+    topic, current := declared[name], actual[name]
+    change := &Change{Topic: name, Action: ACTION_NONE}
+
+    switch {
+    case topic == nil:
+      change.Action = ACTION_UNDECLARED
+
+    case current == nil:
+      change.Action = ACTION_CREATE
+      change.Details = append(change.Details, fmt.Sprintf("partitions %d, replication %d", topic.Partitions, topic.Replication))
+      command := fmt.Sprintf("%s --create --topic %s --partitions %d --replication-factor %d", TOPICS_COMMAND, name, topic.Partitions, topic.Replication)
+      for _, key := range util.SortedKeys(topic.Configs) {
+        command += " --config " + key + "=" + topic.Configs[key]
+        change.Details = append(change.Details, key + "=" + topic.Configs[key])
+      }
+      change.Commands = append(change.Commands, command)
+
+    default:
+      if topic.Partitions > current.Partitions {
+        change.Action = ACTION_ALTER
+        change.Details = append(change.Details, fmt.Sprintf("partitions %d -> %d", current.Partitions, topic.Partitions))
+        change.Commands = append(change.Commands, fmt.Sprintf("%s --alter --topic %s --partitions %d", TOPICS_COMMAND, name, topic.Partitions))
+      }
+
+      var configs []string
+      for _, key := range util.SortedKeys(topic.Configs) {
+        if current.Configs[key] == topic.Configs[key] { continue }
+        change.Action = ACTION_ALTER
+        change.Details = append(change.Details, fmt.Sprintf("%s: '%s' -> '%s'", key, current.Configs[key], topic.Configs[key]))
+        configs = append(configs, key + "=" + topic.Configs[key])
+      }
+      if len(configs) > 0 {
+        change.Commands = append(change.Commands, fmt.Sprintf("%s --entity-name %s --alter --add-config %s", CONFIGS_COMMAND, name, strings.Join(configs, ",")))
+      }
+
+      /* these differences need topic to be recreated, devlab doesn't delete data */
+      if topic.Partitions < current.Partitions {
+        change.Action = ACTION_CONFLICT
+        change.Details = append(change.Details, fmt.Sprintf("partitions %d -> %d couldn't be decreased", current.Partitions, topic.Partitions))
+      }
+      if topic.Replication != current.Replication {
+        change.Action = ACTION_CONFLICT
+        change.Details = append(change.Details, fmt.Sprintf("replication %d -> %d couldn't be changed", current.Replication, topic.Replication))
+      }
+    }
+
+    changes = append(changes, change)
+  }
+
+  return
+}
+
+/**
+* Returns actual topics of broker, exec runs command inside container of broker
+*/
+func Describe(exec func(command string) (string, error)) (map[string]*Topic, error) {
+  out, err := exec(TOPICS_COMMAND + " --describe")
+  if err != nil { return nil, err }
+
+  return ParseDescribe(out), nil
+}
+
+/**
+* Creates and alters topics of changes (conflicts are not resolved)
+*/
+func Apply(changes []*Change, exec func(command string) (string, error)) error {
+  for _, change := range changes {
+    for _, command := range change.Commands {
+      if _, err := exec(command); err != nil {
+        return fmt.Errorf("kafka topic '%s': %s", change.Topic, err)
+      }
+    }
+  }
+
+  return nil
+}
+
+/**
+* Parses configs of topic: <key>=<value>,<key>=<value>
+*/
+func parseConfigs(value string) (configs map[string]string, err error) {
+  configs = make(map[string]string)
+  for _, pair := range strings.Split(value, ",") {
+    if pair = strings.TrimSpace(pair); pair == "" { continue }
+
+    key, configValue, ok := strings.Cut(pair, "=")
+    if !ok || key == "" {
+      return nil, fmt.Errorf("config '%s' should be <key>=<value>", pair)
+    }
+    configs[strings.TrimSpace(key)] = strings.TrimSpace(configValue)
+  }
+
+  return
+}
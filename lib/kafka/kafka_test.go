@@ -0,0 +1,188 @@
+package kafka
+
+import (
+  "reflect"
+  "testing"
+)
+
+const DESCRIBE_OUT = `Topic: orders	TopicId: 3fGcA0XWQd6v1QbY1aE7Jg	PartitionCount: 3	ReplicationFactor: 1	Configs: retention.ms=86400000,cleanup.policy=delete
+	Topic: orders	Partition: 0	Leader: 1	Replicas: 1	Isr: 1
+	Topic: orders	Partition: 1	Leader: 1	Replicas: 1	Isr: 1
+	Topic: orders	Partition: 2	Leader: 1	Replicas: 1	Isr: 1
+Topic: __consumer_offsets	TopicId: 9sd0Xk1nQm2lVbZ8a2E1Ng	PartitionCount: 50	ReplicationFactor: 1	Configs: compression.type=producer,cleanup.policy=compact
+	Topic: __consumer_offsets	Partition: 0	Leader: 1	Replicas: 1	Isr: 1
+Topic: payments	PartitionCount:1	ReplicationFactor:1	Configs:
+	Topic: payments	Partition: 0	Leader: 1	Replicas: 1	Isr: 1
+`
+
+func TestParseDescribe(t *testing.T) {
+  topics := ParseDescribe(DESCRIBE_OUT)
+
+  expected := map[string]*Topic{
+    "orders": {Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "86400000", "cleanup.policy": "delete"}},
+    "__consumer_offsets": {Name: "__consumer_offsets", Partitions: 50, Replication: 1, Configs: map[string]string{"compression.type": "producer", "cleanup.policy": "compact"}},
+    "payments": {Name: "payments", Partitions: 1, Replication: 1, Configs: map[string]string{}},
+  }
+  if !reflect.DeepEqual(topics, expected) {
+    t.Errorf("expected:\n%v\ngot:\n%v", expected, topics)
+  }
+
+  if topics := ParseDescribe(""); len(topics) != 0 {
+    t.Errorf("broker without topics: expected no topics, got %v", topics)
+  }
+}
+
+func TestDiff(t *testing.T) {
+  tests := []struct {
+    name string
+    declared *Topic
+    /* topic of broker (there is no topic if nil) */
+    actual *Topic
+    action string
+    details []string
+    commands []string
+  }{
+    {
+      name: "create",
+      declared: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "1000", "cleanup.policy": "delete"}},
+      action: ACTION_CREATE,
+      details: []string{"partitions 3, replication 1", "cleanup.policy=delete", "retention.ms=1000"},
+      commands: []string{TOPICS_COMMAND + " --create --topic orders --partitions 3 --replication-factor 1 --config cleanup.policy=delete --config retention.ms=1000"},
+    },
+    {
+      name: "same topic",
+      declared: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "1000"}},
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "1000", "segment.ms": "100"}},
+      action: ACTION_NONE,
+    },
+    {
+      name: "alter partitions",
+      declared: &Topic{Name: "orders", Partitions: 6, Replication: 1, Configs: map[string]string{}},
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{}},
+      action: ACTION_ALTER,
+      details: []string{"partitions 3 -> 6"},
+      commands: []string{TOPICS_COMMAND + " --alter --topic orders --partitions 6"},
+    },
+    {
+      name: "add config",
+      declared: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "2000", "cleanup.policy": "compact"}},
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "1000"}},
+      action: ACTION_ALTER,
+      details: []string{"cleanup.policy: '' -> 'compact'", "retention.ms: '1000' -> '2000'"},
+      commands: []string{CONFIGS_COMMAND + " --entity-name orders --alter --add-config cleanup.policy=compact,retention.ms=2000"},
+    },
+    {
+      name: "replication conflict",
+      declared: &Topic{Name: "orders", Partitions: 3, Replication: 3, Configs: map[string]string{"retention.ms": "2000"}},
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{"retention.ms": "1000"}},
+      action: ACTION_CONFLICT,
+      details: []string{"retention.ms: '1000' -> '2000'", "replication 1 -> 3 couldn't be changed"},
+      commands: []string{CONFIGS_COMMAND + " --entity-name orders --alter --add-config retention.ms=2000"},
+    },
+    {
+      name: "partitions conflict",
+      declared: &Topic{Name: "orders", Partitions: 1, Replication: 1, Configs: map[string]string{}},
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{}},
+      action: ACTION_CONFLICT,
+      details: []string{"partitions 3 -> 1 couldn't be decreased"},
+    },
+    {
+      name: "undeclared",
+      actual: &Topic{Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{}},
+      action: ACTION_UNDECLARED,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      declared, actual := map[string]*Topic{}, map[string]*Topic{}
+      if test.declared != nil {
+        declared[test.declared.Name] = test.declared
+      }
+      if test.actual != nil {
+        actual[test.actual.Name] = test.actual
+      }
+
+      changes := Diff(declared, actual)
+      if len(changes) != 1 {
+        t.Fatalf("expected 1 change, got %d", len(changes))
+      }
+      change := changes[0]
+      if change.Topic != "orders" || change.Action != test.action {
+        t.Errorf("expected action '%s' of 'orders', got '%s' of '%s'", test.action, change.Action, change.Topic)
+      }
+      if !reflect.DeepEqual(change.Details, test.details) {
+        t.Errorf("expected details %q, got %q", test.details, change.Details)
+      }
+      if !reflect.DeepEqual(change.Commands, test.commands) {
+        t.Errorf("expected commands:\n%q\ngot:\n%q", test.commands, change.Commands)
+      }
+    })
+  }
+}
+
+func TestDiffOrderAndInternalTopics(t *testing.T) {
+  declared := map[string]*Topic{
+    "payments": {Name: "payments", Partitions: 1, Replication: 1, Configs: map[string]string{}},
+    "orders": {Name: "orders", Partitions: 3, Replication: 1, Configs: map[string]string{}},
+  }
+
+  var topics []string
+  for _, change := range Diff(declared, ParseDescribe(DESCRIBE_OUT)) {
+    topics = append(topics, change.Topic + " " + change.Action)
+  }
+
+  expected := []string{"orders " + ACTION_NONE, "payments " + ACTION_NONE}
+  if !reflect.DeepEqual(topics, expected) {
+    t.Errorf("expected changes %v sorted by topic without internal topics, got %v", expected, topics)
+  }
+}
+
+func TestDeclared(t *testing.T) {
+  tests := []struct {
+    name string
+    params map[string]string
+    expected *Topic
+    isError bool
+  }{
+    {
+      name: "defaults",
+      params: map[string]string{},
+      expected: &Topic{Name: "orders", Partitions: 1, Replication: 1, Configs: map[string]string{}},
+    },
+    {
+      name: "all params",
+      params: map[string]string{"partitions": "3", "replication": "2", "configs": "retention.ms=1000, cleanup.policy=delete"},
+      expected: &Topic{Name: "orders", Partitions: 3, Replication: 2, Configs: map[string]string{"retention.ms": "1000", "cleanup.policy": "delete"}},
+    },
+    {
+      name: "not positive partitions",
+      params: map[string]string{"partitions": "0"},
+      isError: true,
+    },
+    {
+      name: "config without value",
+      params: map[string]string{"configs": "retention.ms"},
+      isError: true,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      topics, err := Declared(map[string]map[string]map[string]string{TOPICS_SECTION: {"orders": test.params}})
+      if test.isError {
+        if err == nil {
+          t.Errorf("expected error, got %v", topics)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      if !reflect.DeepEqual(topics["orders"], test.expected) {
+        t.Errorf("expected %+v, got %+v", test.expected, topics["orders"])
+      }
+    })
+  }
+}
@@ -0,0 +1,56 @@
+package kafka
+
+import (
+  "fmt"
+  "strings"
+  "time"
+  "devlab/lib/deploy"
+  "devlab/lib/logger"
+)
+
+/* How long to wait for broker before topics are provisioned */
+const BROKER_TIMEOUT = 3 * time.Minute
+
+/**
+* Creates and alters topics declared in settings.yml of context once broker is healthy (used by 'devlab up')
+*/
+func Provision(backend deploy.Backend, context map[string]map[string]map[string]string) error {
+  if len(context[TOPICS_SECTION]) == 0 { return nil }
+
+  if err := backend.Wait(BROKER_TIMEOUT, BROKER_SERVICE); err != nil { return err }
+
+  changes, err := Changes(backend, context)
+  if err != nil { return err }
+
+  for _, change := range changes {
+    if change.Action == ACTION_CONFLICT {
+      logger.Warn("kafka topic '%s': %s\n", change.Topic, strings.Join(change.Details, "; "))
+    }
+  }
+
+  return Apply(changes, Exec(backend))
+}
+
+/**
+* Returns differences between declared topics and topics of broker
+*/
+func Changes(backend deploy.Backend, context map[string]map[string]map[string]string) ([]*Change, error) {
+  declared, err := Declared(context)
+  if err != nil { return nil, err }
+
+  actual, err := Describe(Exec(backend))
+  if err != nil {
+    return nil, fmt.Errorf("couldn't read topics of broker (is service '%s' running?): %s", BROKER_SERVICE, err)
+  }
+
+  return Diff(declared, actual), nil
+}
+
+/**
+* Returns function running command inside container of broker
+*/
+func Exec(backend deploy.Backend) func(command string) (string, error) {
+  return func(command string) (string, error) {
+    return backend.Exec(BROKER_SERVICE, command)
+  }
+}
@@ -0,0 +1,283 @@
+package keycloak
+
+import (
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/url"
+  "path/filepath"
+  "regexp"
+  "strings"
+  "time"
+  "devlab/lib/files"
+  "devlab/lib/secrets"
+  "devlab/lib/util"
+)
+
+/* Sections of settings.yml with clients (<client>: {secret, public, redirect-uris}), roles (<role>: {description})
+* and test users (<user>: {password, email, first-name, last-name, roles}) of realm of context */
+const CLIENTS_SECTION = "keycloak-clients"
+const ROLES_SECTION = "keycloak-roles"
+const USERS_SECTION = "keycloak-users"
+
+/* Setting of context section with name of realm */
+const SETTINGS_GROUP = "keycloak"
+const REALM_KEY = "realm"
+const DEFAULT_REALM = "devlab"
+
+/* Service of keycloak, realm is managed with admin cli run inside its container */
+const SERVICE = "keycloak"
+const PORT = 8080
+const ADMIN_COMMAND = "/opt/keycloak/bin/kcadm.sh"
+/* admin credentials are taken from environment of container */
+const LOGIN_COMMAND = ADMIN_COMMAND + ` config credentials --server http://localhost:8080 --realm master --user "$KEYCLOAK_ADMIN" --password "$KEYCLOAK_ADMIN_PASSWORD"`
+
+/* Realm representation of component, realm of context is built on it */
+const REALM_TEMPLATE = "realm.json"
+
+const REQUEST_TIMEOUT = 10 * time.Second
+
+var client = &http.Client{Timeout: REQUEST_TIMEOUT}
+
+var realmNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+type Role struct {
+  Name string `json:"name"`
+  Description string `json:"description,omitempty"`
+}
+
+type Client struct {
+  ClientId string `json:"clientId"`
+  Enabled bool `json:"enabled"`
+  PublicClient bool `json:"publicClient"`
+  Secret string `json:"secret,omitempty"`
+  RedirectUris []string `json:"redirectUris"`
+  WebOrigins []string `json:"webOrigins"`
+  StandardFlowEnabled bool `json:"standardFlowEnabled"`
+  /* tokens of test users are requested with password grant */
+  DirectAccessGrantsEnabled bool `json:"directAccessGrantsEnabled"`
+  ServiceAccountsEnabled bool `json:"serviceAccountsEnabled"`
+}
+
+type Credential struct {
+  Type string `json:"type"`
+  Value string `json:"value"`
+  Temporary bool `json:"temporary"`
+}
+
+type User struct {
+  Username string `json:"username"`
+  Enabled bool `json:"enabled"`
+  Email string `json:"email,omitempty"`
+  EmailVerified bool `json:"emailVerified"`
+  FirstName string `json:"firstName,omitempty"`
+  LastName string `json:"lastName,omitempty"`
+  Credentials []*Credential `json:"credentials"`
+  RealmRoles []string `json:"realmRoles,omitempty"`
+}
+
+/**
+* Realm of context declared in settings.yml
+*/
+type Realm struct {
+  Name string
+  Roles []*Role
+  Clients []*Client
+  Users []*User
+}
+
+/**
+* Returns realm declared in settings.yml of context, secret references of client secrets and passwords are resolved
+*/
+func Declared(context map[string]map[string]map[string]string) (realm *Realm, err error) {
+  /* lists are not nil, so empty ones are imported as [] */
+  realm = &Realm{Name: context["context"][SETTINGS_GROUP][REALM_KEY], Roles: []*Role{}, Clients: []*Client{}, Users: []*User{}}
+  if realm.Name == "" {
+    realm.Name = DEFAULT_REALM
+  }
+  if !realmNamePattern.MatchString(realm.Name) {
+    return nil, fmt.Errorf("keycloak realm '%s': name should contain letters, digits, '.', '_' and '-'", realm.Name)
+  }
+
+  isRole := make(map[string]bool)
+  for _, name := range util.SortedKeys(context[ROLES_SECTION]) {
+    realm.Roles = append(realm.Roles, &Role{Name: name, Description: context[ROLES_SECTION][name]["description"]})
+    isRole[name] = true
+  }
+
+  for _, clientId := range util.SortedKeys(context[CLIENTS_SECTION]) {
+    params := context[CLIENTS_SECTION][clientId]
+    realmClient := &Client{ClientId: clientId, Enabled: true, PublicClient: params["public"] == "true", WebOrigins: []string{"+"},
+      StandardFlowEnabled: true, DirectAccessGrantsEnabled: true}
+    realmClient.RedirectUris = list(params["redirect-uris"])
+    if len(realmClient.RedirectUris) == 0 {
+      realmClient.RedirectUris = []string{"*"}
+    }
+
+    if !realmClient.PublicClient {
+      realmClient.ServiceAccountsEnabled = true
+      if params["secret"] == "" {
+        return nil, fmt.Errorf("keycloak client '%s': secret should be set for confidential client (or set 'public: true')", clientId)
+      }
+      if realmClient.Secret, err = secrets.Resolve(params["secret"]); err != nil {
+        return nil, fmt.Errorf("keycloak client '%s': %s", clientId, err)
+      }
+    }
+    realm.Clients = append(realm.Clients, realmClient)
+  }
+
+  for _, username := range util.SortedKeys(context[USERS_SECTION]) {
+    params := context[USERS_SECTION][username]
+    user := &User{Username: username, Enabled: true, Email: params["email"], EmailVerified: params["email"] != "",
+      FirstName: params["first-name"], LastName: params["last-name"], RealmRoles: list(params["roles"])}
+
+    password, err := secrets.Resolve(params["password"])
+    if err != nil {
+      return nil, fmt.Errorf("keycloak user '%s': %s", username, err)
+    }
+    if password == "" {
+      return nil, fmt.Errorf("keycloak user '%s': password should be set", username)
+    }
+    user.Credentials = []*Credential{{Type: "password", Value: password}}
+
+    for _, role := range user.RealmRoles {
+      if !isRole[role] {
+        return nil, fmt.Errorf("keycloak user '%s': role '%s' is not declared in %s", username, role, ROLES_SECTION)
+      }
+    }
+    realm.Users = append(realm.Users, user)
+  }
+
+  return
+}
+
+/**
+* Returns representation of realm imported into keycloak: realm template of component with declared clients,
+* roles and users
+*/
+func (realm *Realm) Representation(componentDir string) (string, error) {
+  path := filepath.Join(componentDir, REALM_TEMPLATE)
+  data, err := files.ReadTextFile(path)
+  if err != nil { return "", err }
+
+  representation := make(map[string]interface{})
+  if err = json.Unmarshal([]byte(data), &representation); err != nil {
+    return "", fmt.Errorf("%s: %s", path, err)
+  }
+
+  representation["realm"] = realm.Name
+  representation["roles"] = map[string]interface{}{"realm": realm.Roles}
+  representation["clients"] = realm.Clients
+  representation["users"] = realm.Users
+
+  result, err := json.Marshal(representation)
+  return string(result), err
+}
+
+/**
+* Returns partial import of declared clients, roles and users which overwrites existing ones
+*/
+func (realm *Realm) PartialImport() (string, error) {
+  result, err := json.Marshal(map[string]interface{}{
+    "ifResourceExists": "OVERWRITE",
+    "roles": map[string]interface{}{"realm": realm.Roles},
+    "clients": realm.Clients,
+    "users": realm.Users,
+  })
+
+  return string(result), err
+}
+
+/**
+* Returns command which checks if realm exists
+*/
+func (realm *Realm) GetCommand() string {
+  return fmt.Sprintf("%s && %s get realms/%s --fields realm", LOGIN_COMMAND, ADMIN_COMMAND, realm.Name)
+}
+
+/**
+* Returns command which creates realm of representation read from stdin
+*/
+func (realm *Realm) CreateCommand() string {
+  return fmt.Sprintf("%s && %s create realms -f -", LOGIN_COMMAND, ADMIN_COMMAND)
+}
+
+/**
+* Returns command which imports clients, roles and users read from stdin into realm
+*/
+func (realm *Realm) ImportCommand() string {
+  return fmt.Sprintf("%s && %s create partialImport -r %s -f -", LOGIN_COMMAND, ADMIN_COMMAND, realm.Name)
+}
+
+/**
+* Returns user of realm
+*/
+func (realm *Realm) User(username string) (*User, error) {
+  for _, user := range realm.Users {
+    if user.Username == username { return user, nil }
+  }
+
+  return nil, fmt.Errorf("keycloak user '%s' is not declared in %s section of settings.yml", username, USERS_SECTION)
+}
+
+/**
+* Returns client of realm (the first one if clientId is empty)
+*/
+func (realm *Realm) Client(clientId string) (*Client, error) {
+  if len(realm.Clients) == 0 {
+    return nil, fmt.Errorf("there are no keycloak clients in %s section of settings.yml", CLIENTS_SECTION)
+  }
+  if clientId == "" { return realm.Clients[0], nil }
+
+  for _, realmClient := range realm.Clients {
+    if realmClient.ClientId == clientId { return realmClient, nil }
+  }
+
+  return nil, fmt.Errorf("keycloak client '%s' is not declared in %s section of settings.yml", clientId, CLIENTS_SECTION)
+}
+
+/**
+* Requests access token of user with password grant of client
+*/
+func Token(keycloakUrl string, realmName string, realmClient *Client, user *User) (string, error) {
+  form := url.Values{
+    "grant_type": {"password"},
+    "client_id": {realmClient.ClientId},
+    "username": {user.Username},
+    "password": {user.Credentials[0].Value},
+  }
+  if !realmClient.PublicClient {
+    form.Set("client_secret", realmClient.Secret)
+  }
+
+  response, err := client.PostForm(keycloakUrl + "/realms/" + url.PathEscape(realmName) + "/protocol/openid-connect/token", form)
+  if err != nil { return "", err }
+  defer response.Body.Close()
+
+  var result struct {
+    AccessToken string `json:"access_token"`
+    Error string `json:"error"`
+    ErrorDescription string `json:"error_description"`
+  }
+  if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+    return "", fmt.Errorf("keycloak responded with status %d", response.StatusCode)
+  }
+  if response.StatusCode >= 400 || result.AccessToken == "" {
+    return "", fmt.Errorf("keycloak couldn't issue token: %s %s", result.Error, result.ErrorDescription)
+  }
+
+  return result.AccessToken, nil
+}
+
+/**
+* Splits comma separated list
+*/
+func list(value string) (values []string) {
+  for _, item := range strings.Split(value, ",") {
+    if item = strings.TrimSpace(item); item != "" {
+      values = append(values, item)
+    }
+  }
+
+  return
+}
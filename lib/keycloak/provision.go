@@ -0,0 +1,64 @@
+package keycloak
+
+import (
+  "fmt"
+  "io"
+  "strings"
+  "time"
+  "devlab/lib/deploy"
+  "devlab/lib/library"
+  "devlab/lib/logger"
+)
+
+/* How long to wait for keycloak before realm is provisioned */
+const KEYCLOAK_TIMEOUT = 3 * time.Minute
+
+/**
+* Provisions realm declared in settings.yml of context if it has clients, roles or users (used by 'devlab up')
+*/
+func Provision(backend deploy.Backend, target *deploy.Target) error {
+  if len(target.Context[CLIENTS_SECTION]) == 0 && len(target.Context[ROLES_SECTION]) == 0 &&
+    len(target.Context[USERS_SECTION]) == 0 { return nil }
+
+  return Apply(backend, target)
+}
+
+/**
+* Creates realm declared in settings.yml of context or imports its clients, roles and users into existing realm
+* once keycloak is healthy
+*/
+func Apply(backend deploy.Backend, target *deploy.Target) error {
+  realm, err := Declared(target.Context)
+  if err != nil { return err }
+
+  componentsLibrary, err := library.ForContext(target.Config, target.ContextDir)
+  if err != nil { return err }
+  component := componentsLibrary.Find(SERVICE)
+  if component == nil {
+    return fmt.Errorf("component '%s' is not found in library, see 'devlab library list'", SERVICE)
+  }
+
+  if err = backend.Wait(KEYCLOAK_TIMEOUT, SERVICE); err != nil { return err }
+
+  out, err := backend.Exec(SERVICE, realm.GetCommand())
+  if err == nil {
+    data, err := realm.PartialImport()
+    if err != nil { return err }
+
+    if err = backend.Stream(SERVICE, realm.ImportCommand(), strings.NewReader(data), io.Discard); err != nil {
+      return fmt.Errorf("keycloak realm '%s': %s", realm.Name, err)
+    }
+    return nil
+  }
+  if !strings.Contains(strings.ToLower(out), "not found") { return err }
+
+  data, err := realm.Representation(component.Dir)
+  if err != nil { return err }
+
+  if err = backend.Stream(SERVICE, realm.CreateCommand(), strings.NewReader(data), io.Discard); err != nil {
+    return fmt.Errorf("keycloak realm '%s': %s", realm.Name, err)
+  }
+  logger.Info("Keycloak realm '%s' is created\n", realm.Name)
+
+  return nil
+}
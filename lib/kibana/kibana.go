@@ -0,0 +1,81 @@
+package kibana
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/url"
+  "time"
+)
+
+/* Index pattern of logs shipped by logspout to logstash */
+const INDEX_PATTERN_ID = "devlab-logs"
+const INDEX_PATTERN_TITLE = "logstash-*"
+const INDEX_PATTERN_TIME_FIELD = "@timestamp"
+
+/* Container port of kibana */
+const PORT = 5601
+
+const REQUEST_TIMEOUT = 10 * time.Second
+
+var client = &http.Client{Timeout: REQUEST_TIMEOUT}
+
+/**
+* Creates index pattern of devlab logs (if it doesn't exist) and makes it default index pattern of kibana
+*/
+func ProvisionIndexPattern(kibanaUrl string) error {
+  status, err := request(http.MethodGet, kibanaUrl + "/api/saved_objects/index-pattern/" + INDEX_PATTERN_ID, nil)
+  if err != nil { return err }
+
+  if status == http.StatusNotFound {
+    indexPattern := map[string]interface{}{
+      "attributes": map[string]string{"title": INDEX_PATTERN_TITLE, "timeFieldName": INDEX_PATTERN_TIME_FIELD}}
+    if status, err = request(http.MethodPost, kibanaUrl + "/api/saved_objects/index-pattern/" + INDEX_PATTERN_ID, indexPattern); err != nil { return err }
+    if status >= 400 {
+      return fmt.Errorf("kibana couldn't create index pattern '%s': status %d", INDEX_PATTERN_TITLE, status)
+    }
+  } else if status >= 400 {
+    return fmt.Errorf("kibana couldn't read index pattern '%s': status %d", INDEX_PATTERN_TITLE, status)
+  }
+
+  settings := map[string]interface{}{"changes": map[string]string{"defaultIndex": INDEX_PATTERN_ID}}
+  if status, err = request(http.MethodPost, kibanaUrl + "/api/kibana/settings", settings); err != nil { return err }
+  if status >= 400 {
+    return fmt.Errorf("kibana couldn't set default index pattern: status %d", status)
+  }
+
+  return nil
+}
+
+/**
+* Returns url of kibana discover with logs of context
+*/
+func DiscoverUrl(kibanaUrl string, contextName string) string {
+  query := url.QueryEscape("context:\"" + contextName + "\"")
+  return kibanaUrl + "/app/kibana#/discover?_g=(time:(from:now-1h,mode:quick,to:now))&_a=(index:'" + INDEX_PATTERN_ID + "',query:(language:lucene,query:'" + query + "'))"
+}
+
+/**
+* Sends json request to kibana API, returns status of response
+*/
+func request(method string, requestUrl string, body interface{}) (status int, err error) {
+  var data []byte
+  if body != nil {
+    if data, err = json.Marshal(body); err != nil { return }
+  }
+
+  httpRequest, err := http.NewRequest(method, requestUrl, bytes.NewReader(data))
+  if err != nil { return }
+  httpRequest.Header.Set("Content-Type", "application/json")
+  /* required by kibana for requests changing data */
+  httpRequest.Header.Set("kbn-xsrf", "devlab")
+
+  response, err := client.Do(httpRequest)
+  if err != nil {
+    return 0, fmt.Errorf("kibana is not available at %s: %s", requestUrl, err)
+  }
+  response.Body.Close()
+
+  return response.StatusCode, nil
+}
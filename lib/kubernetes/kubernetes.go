@@ -0,0 +1,281 @@
+package kubernetes
+
+import (
+  "encoding/base64"
+  "fmt"
+  "os"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/files"
+  "devlab/lib/health"
+  "devlab/lib/yml"
+)
+
+const NAME_LABEL = "app.kubernetes.io/name"
+const PART_OF_LABEL = "app.kubernetes.io/part-of"
+
+/**
+* Services of context to deploy to kubernetes: services of generated docker compose files with expanded
+* variables and environment (values which hold secrets are put in Secret, other values in ConfigMap)
+*/
+type Model struct {
+  Namespace string
+  /* value of app.kubernetes.io/part-of label of all resources (devlab-<context>) */
+  Project string
+  Services map[string]*DockerComposeFileBuilder.Service
+  Environment map[string]map[string]string
+  Secrets map[string]map[string]string
+  /* health checks are rendered as readiness probes */
+  Checks map[string]*health.Check
+}
+
+/**
+* Kubernetes resource
+*/
+type Manifest struct {
+  Kind string
+  Name string
+  Document interface{}
+}
+
+/**
+* Renders manifests of model: Namespace and for every service (sorted by name) ConfigMap, Secret, Deployment
+* and Service (if service has ports). Result doesn't depend on anything but model, returns warnings about
+* parts of docker compose services which have no kubernetes equivalent
+*/
+func Render(model *Model) (manifests []Manifest, warnings []string) {
+  manifests = append(manifests, Manifest{"Namespace", model.Namespace, &Namespace{
+    ApiVersion: "v1",
+    Kind: "Namespace",
+    Metadata: Metadata{Name: model.Namespace, Labels: map[string]string{PART_OF_LABEL: model.Project}}}})
+
+  serviceNames := make([]string, 0, len(model.Services))
+  for serviceName := range model.Services {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  for _, serviceName := range serviceNames {
+    service := model.Services[serviceName]
+
+    if len(service.Volumes) > 0 {
+      warnings = append(warnings, fmt.Sprintf("service '%s': volumes are not supported by kubernetes strategy, they are skipped", serviceName))
+    }
+    if service.Build != nil {
+      warnings = append(warnings, fmt.Sprintf("service '%s': build is not supported by kubernetes strategy, image '%s' should be built and available for cluster", serviceName, service.Image))
+    }
+
+    container := Container{
+      Name: serviceName,
+      Image: service.Image,
+      Command: commandArgs(service.Entrypoint),
+      Args: commandArgs(service.Command),
+      WorkingDir: service.WorkingDir,
+      Ports: containerPorts(service),
+      ReadinessProbe: readinessProbe(model.Checks[serviceName])}
+
+    if environment := model.Environment[serviceName]; len(environment) > 0 {
+      manifests = append(manifests, Manifest{"ConfigMap", serviceName + "-env", &ConfigMap{
+        ApiVersion: "v1",
+        Kind: "ConfigMap",
+        Metadata: metadata(model, serviceName + "-env", serviceName),
+        Data: environment}})
+      container.EnvFrom = append(container.EnvFrom, EnvFromSource{ConfigMapRef: &Reference{serviceName + "-env"}})
+    }
+
+    if serviceSecrets := model.Secrets[serviceName]; len(serviceSecrets) > 0 {
+      data := make(map[string]string)
+      for name, value := range serviceSecrets {
+        data[name] = base64.StdEncoding.EncodeToString([]byte(value))
+      }
+
+      manifests = append(manifests, Manifest{"Secret", serviceName + "-secret", &Secret{
+        ApiVersion: "v1",
+        Kind: "Secret",
+        Metadata: metadata(model, serviceName + "-secret", serviceName),
+        Type: "Opaque",
+        Data: data}})
+      container.EnvFrom = append(container.EnvFrom, EnvFromSource{SecretRef: &Reference{serviceName + "-secret"}})
+    }
+
+    if limits := resourceLimits(service); len(limits) > 0 {
+      container.Resources = &ResourceRequirements{Limits: limits}
+    }
+
+    replicas := 1
+    if service.Deploy != nil && service.Deploy.Replicas > 0 {
+      replicas = service.Deploy.Replicas
+    }
+
+    manifests = append(manifests, Manifest{"Deployment", serviceName, &Deployment{
+      ApiVersion: "apps/v1",
+      Kind: "Deployment",
+      Metadata: metadata(model, serviceName, serviceName),
+      Spec: DeploymentSpec{
+        Replicas: replicas,
+        Selector: Selector{labels(model, serviceName)},
+        Template: PodTemplate{
+          Metadata: Metadata{Labels: labels(model, serviceName)},
+          Spec: PodSpec{[]Container{container}}}}}})
+
+    if len(container.Ports) == 0 { continue }
+
+    var servicePorts []ServicePort
+    for _, port := range container.Ports {
+      servicePorts = append(servicePorts, ServicePort{
+        Name: strings.ToLower(port.Protocol) + "-" + strconv.Itoa(port.ContainerPort),
+        Port: port.ContainerPort,
+        TargetPort: port.ContainerPort,
+        Protocol: port.Protocol})
+    }
+
+    manifests = append(manifests, Manifest{"Service", serviceName, &Service{
+      ApiVersion: "v1",
+      Kind: "Service",
+      Metadata: metadata(model, serviceName, serviceName),
+      Spec: ServiceSpec{Selector: labels(model, serviceName), Ports: servicePorts}}})
+  }
+
+  return
+}
+
+/**
+* Returns name of manifest file: manifests are numbered, so they are applied in order of rendering
+*/
+func (manifest Manifest) FileName(index int) string {
+  return fmt.Sprintf("%02d-%s-%s.yml", index, strings.ToLower(manifest.Kind), manifest.Name)
+}
+
+/**
+* Writes manifests to folder (one file per resource), manifests which were written before are removed
+*/
+func Write(dir string, manifests []Manifest) (err error) {
+  if err = files.CreateDir(dir); err != nil { return }
+
+  previousManifests, err := filepath.Glob(dir + "/*.yml")
+  if err != nil { return }
+  for _, previousManifest := range previousManifests {
+    if err = os.Remove(previousManifest); err != nil { return }
+  }
+
+  for index, manifest := range manifests {
+    data, err := yml.Marshal(manifest.Document)
+    if err != nil { return err }
+
+    if err = files.WriteFileAtomic(dir + "/" + manifest.FileName(index), string(data)); err != nil { return err }
+  }
+
+  return
+}
+
+/**
+* Returns container ports of published (ports) and exposed (expose) ports of service
+*/
+func containerPorts(service *DockerComposeFileBuilder.Service) (ports []ContainerPort) {
+  isAdded := make(map[string]bool)
+  for _, definition := range append(append([]string{}, service.Ports...), service.Expose...) {
+    parts := strings.Split(definition, ":")
+    number, protocol, _ := strings.Cut(parts[len(parts) - 1], "/")
+
+    portNumber, err := strconv.Atoi(number)
+    if err != nil || isAdded[number + "/" + protocol] { continue }
+    isAdded[number + "/" + protocol] = true
+
+    protocol = strings.ToUpper(protocol)
+    if protocol == "" {
+      protocol = "TCP"
+    }
+    ports = append(ports, ContainerPort{portNumber, protocol})
+  }
+
+  return
+}
+
+/**
+* Returns readiness probe of health check (nil if service has no check)
+*/
+func readinessProbe(check *health.Check) *Probe {
+  if check == nil { return nil }
+
+  probe := &Probe{PeriodSeconds: 5}
+  switch {
+  case check.Tcp != 0:
+    probe.TcpSocket = &TcpSocketAction{check.Tcp}
+  case check.Http != "":
+    port, path, _ := check.HttpPortAndPath()
+    probe.HttpGet = &HttpGetAction{path, port}
+  default:
+    probe.Exec = &ExecAction{[]string{"sh", "-c", check.Command}}
+  }
+
+  return probe
+}
+
+/**
+* Returns command or entrypoint of service as list (string form is split by spaces)
+*/
+func commandArgs(value interface{}) (args []string) {
+  switch typedValue := value.(type) {
+  case string:
+    return strings.Fields(typedValue)
+  case []interface{}:
+    for _, arg := range typedValue {
+      args = append(args, fmt.Sprint(arg))
+    }
+  case []string:
+    return typedValue
+  }
+
+  return
+}
+
+/**
+* Returns resource limits of service (mem_limit and cpus of compose 2.x or deploy.resources.limits of 3.x)
+*/
+func resourceLimits(service *DockerComposeFileBuilder.Service) (limits map[string]string) {
+  limits = make(map[string]string)
+  memory, cpus := service.MemLimit, service.Cpus
+  if service.Deploy != nil && service.Deploy.Resources != nil && service.Deploy.Resources.Limits != nil {
+    if service.Deploy.Resources.Limits.Memory != "" {
+      memory = service.Deploy.Resources.Limits.Memory
+    }
+    if service.Deploy.Resources.Limits.Cpus != "" {
+      cpus = service.Deploy.Resources.Limits.Cpus
+    }
+  }
+
+  if cpus != "" {
+    limits["cpu"] = cpus
+  }
+  if memory != "" {
+    limits["memory"] = memoryQuantity(memory)
+  }
+
+  return
+}
+
+/**
+* Converts docker memory value to kubernetes quantity ('512m' => '512Mi')
+*/
+func memoryQuantity(value string) string {
+  units := map[string]string{"b": "", "k": "Ki", "kb": "Ki", "m": "Mi", "mb": "Mi", "g": "Gi", "gb": "Gi"}
+
+  lowerValue := strings.ToLower(value)
+  number := strings.TrimRight(lowerValue, "bkmg")
+  if unit, ok := units[strings.TrimPrefix(lowerValue, number)]; ok {
+    return number + unit
+  }
+
+  return value
+}
+
+func metadata(model *Model, name string, serviceName string) Metadata {
+  return Metadata{Name: name, Namespace: model.Namespace, Labels: labels(model, serviceName)}
+}
+
+func labels(model *Model, serviceName string) map[string]string {
+  return map[string]string{NAME_LABEL: serviceName, PART_OF_LABEL: model.Project}
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+  "flag"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/health"
+)
+
+/* go test ./lib/kubernetes -update rewrites golden files with rendered manifests */
+var update = flag.Bool("update", false, "update golden files")
+
+const GOLDEN_DIR = "testdata"
+const GOLDEN_SUFFIX = ".golden"
+const WARNINGS_GOLDEN_FILE = "warnings" + GOLDEN_SUFFIX
+
+/**
+* Model of context with application service (command, environment, secret, limits, replicas) and system
+* service with health check and volume
+*/
+func testModel() *Model {
+  return &Model{
+    Namespace: "devlab-foo",
+    Project: "devlab-foo",
+    Services: map[string]*DockerComposeFileBuilder.Service{
+      "dlp-service-config": {
+        Image: "prefix/dlp-service-config:latest",
+        Build: &DockerComposeFileBuilder.Build{Context: "./services/dlp-service-config"},
+        Command: "node server.js --port 4004",
+        WorkingDir: "/app",
+        Ports: []string{"20005:4004"},
+        Deploy: &DockerComposeFileBuilder.Deploy{
+          Replicas: 2,
+          Resources: &DockerComposeFileBuilder.Resources{Limits: &DockerComposeFileBuilder.ResourceValues{Cpus: "0.5", Memory: "512m"}}},
+      },
+      "postgres": {
+        Image: "postgres",
+        Volumes: []string{"pg_data:/var/lib/postgresql/data/"},
+        Ports: []string{"20014:5432"},
+        Expose: []string{"5432"},
+        MemLimit: "1g",
+      },
+    },
+    Environment: map[string]map[string]string{
+      "dlp-service-config": {"POSTGRES_HOST": "postgres", "POSTGRES_PORT": "5432"},
+    },
+    Secrets: map[string]map[string]string{
+      "dlp-service-config": {"POSTGRES_PASSWORD": "p@ss word"},
+      "postgres": {"POSTGRES_PASSWORD": "p@ss word"},
+    },
+    Checks: map[string]*health.Check{
+      "postgres": {Tcp: 5432},
+    },
+  }
+}
+
+func TestRenderAndWrite(t *testing.T) {
+  manifests, warnings := Render(testModel())
+
+  dir := t.TempDir()
+  if err := Write(dir, manifests); err != nil {
+    t.Fatalf("couldn't write manifests: %s", err)
+  }
+
+  written, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+  if err != nil { t.Fatal(err) }
+
+  actual := map[string]string{WARNINGS_GOLDEN_FILE: strings.Join(warnings, "\n") + "\n"}
+  for _, path := range written {
+    data, err := os.ReadFile(path)
+    if err != nil { t.Fatal(err) }
+    actual[filepath.Base(path) + GOLDEN_SUFFIX] = string(data)
+  }
+
+  if *update {
+    goldenFiles, _ := filepath.Glob(filepath.Join(GOLDEN_DIR, "*" + GOLDEN_SUFFIX))
+    for _, path := range goldenFiles {
+      if err = os.Remove(path); err != nil { t.Fatal(err) }
+    }
+    if err = os.MkdirAll(GOLDEN_DIR, 0755); err != nil { t.Fatal(err) }
+    for name, content := range actual {
+      if err = os.WriteFile(filepath.Join(GOLDEN_DIR, name), []byte(content), 0644); err != nil { t.Fatal(err) }
+    }
+    return
+  }
+
+  goldenFiles, err := filepath.Glob(filepath.Join(GOLDEN_DIR, "*" + GOLDEN_SUFFIX))
+  if err != nil { t.Fatal(err) }
+  if len(goldenFiles) != len(actual) {
+    t.Errorf("expected %d files (with warnings), got %d: %v (run with -update to accept)", len(goldenFiles), len(actual), written)
+  }
+
+  for _, path := range goldenFiles {
+    expected, err := os.ReadFile(path)
+    if err != nil { t.Fatal(err) }
+
+    name := filepath.Base(path)
+    content, isWritten := actual[name]
+    if !isWritten {
+      t.Errorf("%s is not rendered", strings.TrimSuffix(name, GOLDEN_SUFFIX))
+      continue
+    }
+    if content != string(expected) {
+      t.Errorf("%s differs from golden file (run with -update to accept):\n%s", strings.TrimSuffix(name, GOLDEN_SUFFIX), content)
+    }
+  }
+}
+
+/**
+* Manifests don't depend on order of map iteration, so the same model is always rendered the same way
+*/
+func TestRenderIsStable(t *testing.T) {
+  first, _ := Render(testModel())
+  for i := 0; i < 10; i++ {
+    next, _ := Render(testModel())
+    if len(next) != len(first) {
+      t.Fatalf("expected %d manifests, got %d", len(first), len(next))
+    }
+    for index := range first {
+      if next[index].FileName(index) != first[index].FileName(index) {
+        t.Fatalf("manifest %d: expected %s, got %s", index, first[index].FileName(index), next[index].FileName(index))
+      }
+    }
+  }
+}
@@ -0,0 +1,127 @@
+package kubernetes
+
+/**
+* Subset of kubernetes resources devlab renders (fields are written in order of declaration)
+*/
+type Metadata struct {
+  Name string `yaml:"name,omitempty"`
+  Namespace string `yaml:"namespace,omitempty"`
+  Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type Namespace struct {
+  ApiVersion string `yaml:"apiVersion"`
+  Kind string `yaml:"kind"`
+  Metadata Metadata `yaml:"metadata"`
+}
+
+type ConfigMap struct {
+  ApiVersion string `yaml:"apiVersion"`
+  Kind string `yaml:"kind"`
+  Metadata Metadata `yaml:"metadata"`
+  Data map[string]string `yaml:"data"`
+}
+
+type Secret struct {
+  ApiVersion string `yaml:"apiVersion"`
+  Kind string `yaml:"kind"`
+  Metadata Metadata `yaml:"metadata"`
+  Type string `yaml:"type"`
+  /* values are base64 encoded */
+  Data map[string]string `yaml:"data"`
+}
+
+type Deployment struct {
+  ApiVersion string `yaml:"apiVersion"`
+  Kind string `yaml:"kind"`
+  Metadata Metadata `yaml:"metadata"`
+  Spec DeploymentSpec `yaml:"spec"`
+}
+
+type DeploymentSpec struct {
+  Replicas int `yaml:"replicas"`
+  Selector Selector `yaml:"selector"`
+  Template PodTemplate `yaml:"template"`
+}
+
+type Selector struct {
+  MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type PodTemplate struct {
+  Metadata Metadata `yaml:"metadata"`
+  Spec PodSpec `yaml:"spec"`
+}
+
+type PodSpec struct {
+  Containers []Container `yaml:"containers"`
+}
+
+type Container struct {
+  Name string `yaml:"name"`
+  Image string `yaml:"image"`
+  Command []string `yaml:"command,omitempty"`
+  Args []string `yaml:"args,omitempty"`
+  WorkingDir string `yaml:"workingDir,omitempty"`
+  Ports []ContainerPort `yaml:"ports,omitempty"`
+  EnvFrom []EnvFromSource `yaml:"envFrom,omitempty"`
+  Resources *ResourceRequirements `yaml:"resources,omitempty"`
+  ReadinessProbe *Probe `yaml:"readinessProbe,omitempty"`
+}
+
+type Probe struct {
+  TcpSocket *TcpSocketAction `yaml:"tcpSocket,omitempty"`
+  HttpGet *HttpGetAction `yaml:"httpGet,omitempty"`
+  Exec *ExecAction `yaml:"exec,omitempty"`
+  PeriodSeconds int `yaml:"periodSeconds,omitempty"`
+}
+
+type TcpSocketAction struct {
+  Port int `yaml:"port"`
+}
+
+type HttpGetAction struct {
+  Path string `yaml:"path"`
+  Port int `yaml:"port"`
+}
+
+type ExecAction struct {
+  Command []string `yaml:"command"`
+}
+
+type ContainerPort struct {
+  ContainerPort int `yaml:"containerPort"`
+  Protocol string `yaml:"protocol"`
+}
+
+type EnvFromSource struct {
+  ConfigMapRef *Reference `yaml:"configMapRef,omitempty"`
+  SecretRef *Reference `yaml:"secretRef,omitempty"`
+}
+
+type Reference struct {
+  Name string `yaml:"name"`
+}
+
+type ResourceRequirements struct {
+  Limits map[string]string `yaml:"limits,omitempty"`
+}
+
+type Service struct {
+  ApiVersion string `yaml:"apiVersion"`
+  Kind string `yaml:"kind"`
+  Metadata Metadata `yaml:"metadata"`
+  Spec ServiceSpec `yaml:"spec"`
+}
+
+type ServiceSpec struct {
+  Selector map[string]string `yaml:"selector"`
+  Ports []ServicePort `yaml:"ports"`
+}
+
+type ServicePort struct {
+  Name string `yaml:"name"`
+  Port int `yaml:"port"`
+  TargetPort int `yaml:"targetPort"`
+  Protocol string `yaml:"protocol"`
+}
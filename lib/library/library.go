@@ -0,0 +1,300 @@
+package library
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strings"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/files"
+  "devlab/lib/health"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+/* Folder of library with components (one folder per component) */
+const COMPONENTS_DIR = "third-party-components"
+
+/* Manifest of component in its folder */
+const COMPONENT_MANIFEST = "component.yml"
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+/**
+* Component of library (<library>/third-party-components/<name>/component.yml). Paths are relative to folder
+* of component, paths in compose files are relative to folder of compose file
+*/
+type Component struct {
+  Name string `yaml:"name"`
+  Description string `yaml:"description"`
+  /* compose file with services of component */
+  Compose string `yaml:"compose"`
+  /* services of compose file provided by component */
+  Services []string `yaml:"services"`
+  /* values are set as params of system service in settings.yml, they are available as <COMPONENT>_<PARAM> variables */
+  Parameters map[string]*Parameter `yaml:"parameters,omitempty"`
+  /* files mounted to containers or used by build (folder of component is shared by contexts, so data of services
+  * is kept in named volumes of compose file, every context gets its own ones) */
+  Files []string `yaml:"files,omitempty"`
+  Healthchecks map[string]*health.Check `yaml:"healthchecks,omitempty"`
+  /* components which are added to context together with component */
+  Dependencies []string `yaml:"dependencies,omitempty"`
+  /* optional parts of component enabled by 'extensions' param of system service (comma separated) */
+  Extensions map[string]*Extension `yaml:"extensions,omitempty"`
+
+  /* folder of component */
+  Dir string `yaml:"-"`
+}
+
+type Parameter struct {
+  Description string `yaml:"description"`
+  Default string `yaml:"default,omitempty"`
+  Required bool `yaml:"required,omitempty"`
+  /* value should be secret reference (secret://<name>) */
+  Secret bool `yaml:"secret,omitempty"`
+}
+
+type Extension struct {
+  Description string `yaml:"description"`
+  Compose string `yaml:"compose"`
+  Services []string `yaml:"services"`
+  Files []string `yaml:"files,omitempty"`
+  Healthchecks map[string]*health.Check `yaml:"healthchecks,omitempty"`
+}
+
+/**
+* Components of library by name
+*/
+type Library struct {
+  Path string
+  Components map[string]*Component
+  /* conflicts of components of sources and sources locked to older versions */
+  Warnings []string
+}
+
+/**
+* Error with all problems found in components of library
+*/
+type ValidationError struct {
+  Problems []string
+}
+
+func (e *ValidationError) Error() string {
+  return "library components are not valid:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+/**
+* Loads manifests of components of library (library without components folder has no components)
+*/
+func Load(libraryPath string) (library *Library, err error) {
+  library = &Library{Path: libraryPath, Components: make(map[string]*Component)}
+
+  manifests, err := filepath.Glob(filepath.Join(libraryPath, COMPONENTS_DIR, "*", COMPONENT_MANIFEST))
+  if err != nil { return }
+
+  for _, manifest := range manifests {
+    data, err := files.ReadTextFile(manifest)
+    if err != nil { return nil, err }
+
+    component := &Component{}
+    if err = yamlv3.Unmarshal([]byte(data), component); err != nil {
+      return nil, fmt.Errorf("%s: %s", manifest, err)
+    }
+    component.Dir = filepath.Dir(manifest)
+
+    if _, isDuplicate := library.Components[component.Name]; isDuplicate {
+      return nil, fmt.Errorf("%s: component '%s' is already defined", manifest, component.Name)
+    }
+    library.Components[component.Name] = component
+  }
+
+  return
+}
+
+/**
+* Returns sorted names of components
+*/
+func (library *Library) Names() []string {
+  names := make([]string, 0, len(library.Components))
+  for name := range library.Components {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  return names
+}
+
+/**
+* Returns component by its name or by name of service it provides (nil if there is no such component)
+*/
+func (library *Library) Find(name string) *Component {
+  if component, ok := library.Components[name]; ok {
+    return component
+  }
+
+  for _, componentName := range library.Names() {
+    for _, serviceName := range library.Components[componentName].Services {
+      if serviceName == name {
+        return library.Components[componentName]
+      }
+    }
+  }
+
+  return nil
+}
+
+/**
+* Returns health checks of services of all components and their extensions
+*/
+func (library *Library) Healthchecks() map[string]*health.Check {
+  checks := make(map[string]*health.Check)
+  for _, component := range library.Components {
+    for serviceName, check := range component.Healthchecks {
+      checks[serviceName] = check
+    }
+    for _, extension := range component.Extensions {
+      for serviceName, check := range extension.Healthchecks {
+        checks[serviceName] = check
+      }
+    }
+  }
+
+  return checks
+}
+
+/**
+* Checks every component: name matches folder, compose files define provided services, files exist,
+* dependencies are components of library, health checks are valid and belong to provided services
+*/
+func (library *Library) Validate() error {
+  var problems []string
+  for _, name := range library.Names() {
+    for _, problem := range library.validateComponent(library.Components[name]) {
+      problems = append(problems, name + ": " + problem)
+    }
+  }
+
+  if len(problems) > 0 {
+    return &ValidationError{problems}
+  }
+  return nil
+}
+
+func (library *Library) validateComponent(component *Component) (problems []string) {
+  addProblem := func(format string, params ...interface{}) {
+    problems = append(problems, fmt.Sprintf(format, params...))
+  }
+
+  if !namePattern.MatchString(component.Name) {
+    addProblem("name should contain lower case letters, digits, '-' and '_'")
+  }
+  if filepath.Base(component.Dir) != component.Name {
+    addProblem("name doesn't match folder '%s'", filepath.Base(component.Dir))
+  }
+  if component.Description == "" {
+    addProblem("description is not set")
+  }
+
+  provided := make(map[string]bool)
+  problems = append(problems, validateServices(component.Dir, component.Compose, component.Services, provided)...)
+  problems = append(problems, validateFiles(component.Dir, component.Files)...)
+
+  for name, parameter := range component.Parameters {
+    if !namePattern.MatchString(name) {
+      addProblem("parameter '%s': name should contain lower case letters, digits, '-' and '_'", name)
+    }
+    if name == "enabled" || name == "depends-on" || name == "extensions" {
+      addProblem("parameter '%s': name is reserved", name)
+    }
+    if parameter == nil || parameter.Description == "" {
+      addProblem("parameter '%s': description is not set", name)
+    } else if parameter.Required && parameter.Default != "" {
+      addProblem("parameter '%s': required parameter couldn't have default", name)
+    }
+  }
+
+  for _, dependency := range component.Dependencies {
+    if _, ok := library.Components[dependency]; !ok {
+      addProblem("dependency '%s' is not component of library", dependency)
+    }
+  }
+
+  extensionNames := make([]string, 0, len(component.Extensions))
+  for name := range component.Extensions {
+    extensionNames = append(extensionNames, name)
+  }
+  sort.Strings(extensionNames)
+
+  checks := component.Healthchecks
+  for _, name := range extensionNames {
+    extension := component.Extensions[name]
+    if extension == nil {
+      addProblem("extension '%s' is empty", name)
+      continue
+    }
+    for _, problem := range validateServices(component.Dir, extension.Compose, extension.Services, provided) {
+      addProblem("extension '%s': %s", name, problem)
+    }
+    for _, problem := range validateFiles(component.Dir, extension.Files) {
+      addProblem("extension '%s': %s", name, problem)
+    }
+    for serviceName, check := range extension.Healthchecks {
+      if checks == nil {
+        checks = make(map[string]*health.Check)
+      }
+      checks[serviceName] = check
+    }
+  }
+
+  for serviceName, check := range checks {
+    if !provided[serviceName] {
+      addProblem("health check of service '%s' which is not provided by component", serviceName)
+    }
+    if err := check.Validate(); err != nil {
+      addProblem("health check of service '%s': %s", serviceName, err)
+    }
+  }
+
+  sort.Strings(problems)
+  return
+}
+
+/**
+* Checks that compose file defines services, adds them to provided services
+*/
+func validateServices(componentDir string, compose string, serviceNames []string, provided map[string]bool) (problems []string) {
+  if compose == "" {
+    return []string{"compose file is not set"}
+  }
+  if len(serviceNames) == 0 {
+    return []string{"services are not set"}
+  }
+
+  dockerComposeData, err := DockerComposeFileBuilder.ReadFile(filepath.Join(componentDir, compose))
+  if err != nil {
+    return []string{err.Error()}
+  }
+
+  for _, serviceName := range serviceNames {
+    if _, ok := dockerComposeData.Services[serviceName]; !ok {
+      problems = append(problems, fmt.Sprintf("service '%s' is not defined in %s", serviceName, compose))
+    }
+    if provided[serviceName] {
+      problems = append(problems, fmt.Sprintf("service '%s' is provided twice", serviceName))
+    }
+    provided[serviceName] = true
+  }
+
+  return
+}
+
+func validateFiles(componentDir string, paths []string) (problems []string) {
+  for _, path := range paths {
+    if _, err := os.Stat(filepath.Join(componentDir, path)); err != nil {
+      problems = append(problems, fmt.Sprintf("file '%s' is not found", path))
+    }
+  }
+
+  return
+}
@@ -0,0 +1,223 @@
+package library
+
+import (
+  "fmt"
+  "path/filepath"
+  "sort"
+  "strings"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/env"
+  "devlab/lib/yml"
+)
+
+/* Observability stacks of 'observability' setting of context ('observability: elk') and their components with extensions */
+var ObservabilityStacks = map[string][]string{"elk": {"elk", "logspout"}}
+
+/* Params of system service in settings.yml which are not parameters of component */
+var reservedParams = map[string]bool{"enabled": true, "depends-on": true, "extensions": true}
+
+/**
+* Renders services of component and its enabled extensions as compose file written to targetDir:
+* relative paths are rewritten relative to targetDir
+*/
+func (library *Library) Render(component *Component, extensions []string, targetDir string) (dockerComposeData *DockerComposeFileBuilder.DockerComposeFile, err error) {
+  dockerComposeData = DockerComposeFileBuilder.New("")
+
+  if err = addServices(dockerComposeData, component.Dir, component.Compose, component.Services, targetDir); err != nil { return }
+
+  for _, extensionName := range extensions {
+    extension, ok := component.Extensions[extensionName]
+    if !ok {
+      return nil, fmt.Errorf("component '%s' has no extension '%s'", component.Name, extensionName)
+    }
+    if err = addServices(dockerComposeData, component.Dir, extension.Compose, extension.Services, targetDir); err != nil { return }
+  }
+
+  return
+}
+
+/**
+* Returns values of parameters of component: defaults overridden by params of system service in settings.yml
+* (params which are not declared by component are kept too), error lists required parameters which are not set
+*/
+func (component *Component) Values(serviceParams map[string]string) (values map[string]string, err error) {
+  values = make(map[string]string)
+  for name, parameter := range component.Parameters {
+    if parameter != nil && parameter.Default != "" {
+      values[name] = parameter.Default
+    }
+  }
+  for name, value := range serviceParams {
+    if !reservedParams[name] {
+      values[name] = value
+    }
+  }
+
+  var missing []string
+  for name, parameter := range component.Parameters {
+    if parameter != nil && parameter.Required && values[name] == "" {
+      missing = append(missing, name)
+    }
+  }
+  if len(missing) > 0 {
+    sort.Strings(missing)
+    return nil, fmt.Errorf("component '%s': required parameters are not set in settings.yml: %s", component.Name, strings.Join(missing, ", "))
+  }
+
+  return
+}
+
+/**
+* Returns names of extensions enabled by 'extensions' param of system service (comma separated)
+*/
+func Extensions(serviceParams map[string]string) (extensions []string) {
+  for _, extension := range strings.Split(serviceParams["extensions"], ",") {
+    if extension = strings.TrimSpace(extension); extension != "" {
+      extensions = append(extensions, extension)
+    }
+  }
+
+  return
+}
+
+/**
+* Builds system docker compose of context from components of library: it contains services of enabled
+* system services (components or services of components), of observability stack and of components they depend on.
+* Returns warnings about system services which are not found in library
+*/
+func (library *Library) SystemCompose(contextDir string, contextName string, context map[string]map[string]map[string]string) (dockerComposeData *DockerComposeFileBuilder.DockerComposeFile, warnings []string, err error) {
+  dockerComposeData = DockerComposeFileBuilder.New("2")
+  dockerComposeData.Networks["default"] = &DockerComposeFileBuilder.Network{Name: DockerComposeFileBuilder.ContextNetwork(contextName, context), External: true}
+
+  /* extensions of components to add */
+  selected := make(map[string][]string)
+  var selectComponent func(component *Component, extensions []string)
+  selectComponent = func(component *Component, extensions []string) {
+    if component == nil { return }
+    _, isSelected := selected[component.Name]
+    selected[component.Name] = append(selected[component.Name], extensions...)
+    if isSelected { return }
+
+    for _, dependency := range component.Dependencies {
+      selectComponent(library.Components[dependency], nil)
+    }
+  }
+
+  serviceNames := make([]string, 0, len(context["system-services"]))
+  for serviceName := range context["system-services"] {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  for _, serviceName := range serviceNames {
+    serviceParams := context["system-services"][serviceName]
+    if serviceParams["enabled"] == "false" { continue }
+
+    component := library.Find(serviceName)
+    if component == nil {
+      warnings = append(warnings, fmt.Sprintf("system service '%s' is not found in library, see 'devlab library list'", serviceName))
+      continue
+    }
+    selectComponent(component, Extensions(serviceParams))
+  }
+
+  if stack := context["context"]["observability"][yml.SETTINGS_VALUE_PARAM]; stack != "" {
+    components, ok := ObservabilityStacks[stack]
+    if !ok {
+      return nil, nil, fmt.Errorf("observability stack '%s' is not supported (supported: elk)", stack)
+    }
+    component := library.Components[components[0]]
+    if component == nil {
+      return nil, nil, fmt.Errorf("component '%s' of observability stack is not found in library", components[0])
+    }
+    selectComponent(component, components[1:])
+  }
+
+  for _, name := range library.Names() {
+    extensions, isSelected := selected[name]
+    if !isSelected { continue }
+
+    componentCompose, err := library.Render(library.Components[name], unique(extensions), contextDir)
+    if err != nil { return nil, nil, err }
+    DockerComposeFileBuilder.Merge(dockerComposeData, componentCompose)
+  }
+
+  return
+}
+
+/**
+* Returns variables of parameters of enabled system services (<COMPONENT>_<PARAM>, e.g. POSTGRES_PASSWORD),
+* values could be secret references
+*/
+func (library *Library) ParameterVariables(context map[string]map[string]map[string]string) (variables map[string]string, err error) {
+  variables = make(map[string]string)
+  for serviceName, serviceParams := range context["system-services"] {
+    if serviceParams["enabled"] == "false" { continue }
+
+    values := make(map[string]string)
+    if component := library.Find(serviceName); component != nil {
+      if values, err = component.Values(serviceParams); err != nil { return }
+    } else {
+      for name, value := range serviceParams {
+        if !reservedParams[name] {
+          values[name] = value
+        }
+      }
+    }
+
+    for name, value := range values {
+      variables[env.Name(serviceName + "_" + name)] = value
+    }
+  }
+
+  return
+}
+
+/**
+* Adds services of compose file of component to compose file written to targetDir
+*/
+func addServices(dockerComposeData *DockerComposeFileBuilder.DockerComposeFile, componentDir string, compose string, serviceNames []string, targetDir string) error {
+  composePath := filepath.Join(componentDir, compose)
+  componentCompose, err := DockerComposeFileBuilder.ReadFile(composePath)
+  if err != nil { return err }
+
+  /* paths of compose file are relative to its folder, they are rewritten relative to target folder */
+  absoluteTargetDir, _ := filepath.Abs(targetDir)
+  absoluteComposeDir, _ := filepath.Abs(filepath.Dir(composePath))
+  relativeComposeDir, err := filepath.Rel(absoluteTargetDir, absoluteComposeDir)
+  if err != nil { return err }
+  DockerComposeFileBuilder.RebasePaths(componentCompose, filepath.ToSlash(relativeComposeDir))
+
+  for _, serviceName := range serviceNames {
+    service, ok := componentCompose.Services[serviceName]
+    if !ok {
+      return fmt.Errorf("%s: service '%s' is not defined", composePath, serviceName)
+    }
+    dockerComposeData.Services[serviceName] = service
+
+    /* named volumes are volumes of compose project, so every context gets its own ones */
+    for _, volume := range service.Volumes {
+      source, _, isMount := strings.Cut(volume, ":")
+      namedVolume, isNamed := componentCompose.Volumes[source]
+      if !isMount || !isNamed { continue }
+      if namedVolume == nil {
+        namedVolume = &DockerComposeFileBuilder.Volume{}
+      }
+      dockerComposeData.Volumes[source] = namedVolume
+    }
+  }
+
+  return nil
+}
+
+func unique(values []string) (result []string) {
+  isAdded := make(map[string]bool)
+  for _, value := range values {
+    if !isAdded[value] {
+      isAdded[value] = true
+      result = append(result, value)
+    }
+  }
+
+  return
+}
@@ -0,0 +1,46 @@
+package library
+
+import (
+  "fmt"
+  "path/filepath"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/files"
+  "devlab/lib/health"
+)
+
+/* Compose file of scaffolded component */
+const COMPONENT_COMPOSE = "docker-compose.yml"
+
+/**
+* Creates folder of new component with component.yml and compose file with one service
+* (image defaults to name of component, port is optional). Returns folder of component
+*/
+func Scaffold(libraryPath string, name string, image string, port int) (componentDir string, err error) {
+  if !namePattern.MatchString(name) {
+    return "", fmt.Errorf("component name '%s' should contain lower case letters, digits, '-' and '_'", name)
+  }
+
+  componentDir = filepath.Join(libraryPath, COMPONENTS_DIR, name)
+  if isExists, _ := files.IsExists(componentDir); isExists {
+    return "", fmt.Errorf("component '%s' already exists: %s", name, componentDir)
+  }
+  if image == "" {
+    image = name
+  }
+
+  service := &DockerComposeFileBuilder.Service{Image: image}
+  component := &Component{Name: name, Description: "TODO: describe " + name, Compose: COMPONENT_COMPOSE, Services: []string{name}}
+  if port > 0 {
+    service.Ports = []string{fmt.Sprintf("%d:%d", port, port)}
+    component.Healthchecks = map[string]*health.Check{name: {Tcp: port}}
+  }
+
+  dockerComposeData := DockerComposeFileBuilder.New("2")
+  dockerComposeData.Services[name] = service
+
+  if err = files.CreateDir(componentDir); err != nil { return }
+  if err = files.WriteYaml(filepath.Join(componentDir, COMPONENT_COMPOSE), dockerComposeData); err != nil { return }
+  err = files.WriteYaml(filepath.Join(componentDir, COMPONENT_MANIFEST), component)
+
+  return
+}
@@ -0,0 +1,429 @@
+package library
+
+import (
+  "archive/tar"
+  "bytes"
+  "compress/gzip"
+  "crypto/sha256"
+  "encoding/hex"
+  "fmt"
+  "io"
+  "net/http"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/util"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+const SOURCE_LOCAL = "local"
+const SOURCE_GIT = "git"
+const SOURCE_TARBALL = "tarball"
+
+/* Prefix of git sources in library-sources (git+<url>@<tag>) */
+const GIT_PREFIX = "git+"
+
+/* Lock file of context with resolved versions of library sources */
+const LOCK_FILE = "library.lock"
+
+/* Folder of cache of fetched sources (in user cache folder) */
+const CACHE_DIR = "devlab/libraries"
+
+/* Timeout of download of tarball source */
+const DOWNLOAD_TIMEOUT = 2 * time.Minute
+
+var client = &http.Client{Timeout: DOWNLOAD_TIMEOUT}
+
+/**
+* Source of components. Sources are set in 'library-sources' of .config (comma separated):
+*   <folder>                     local folder
+*   git+<repository url>@<tag>   git repository pinned to tag
+*   <url or path>.tar.gz         tarball
+* library-path is always the last source
+*/
+type Source struct {
+  Location string `yaml:"source"`
+  Type string `yaml:"type"`
+  /* tag of git source */
+  Ref string `yaml:"ref,omitempty"`
+  /* commit of git source, sha256 of tarball */
+  Resolved string `yaml:"resolved,omitempty"`
+
+  /* folder with fetched source */
+  Dir string `yaml:"-"`
+}
+
+/**
+* Resolved sources of context (<context>/library.lock)
+*/
+type Lock struct {
+  Sources []*Source `yaml:"sources"`
+}
+
+/**
+* Parses 'library-sources' value, library path is added as the last source
+*/
+func ParseSources(value string, libraryPath string) (sources []*Source, err error) {
+  for _, location := range append(strings.Split(value, ","), libraryPath) {
+    location = strings.TrimSpace(location)
+    if location == "" { continue }
+
+    source := &Source{Location: location, Type: SOURCE_LOCAL}
+    switch {
+    case strings.HasPrefix(location, GIT_PREFIX):
+      source.Type = SOURCE_GIT
+      url := strings.TrimPrefix(location, GIT_PREFIX)
+      separator := strings.LastIndex(url, "@")
+      if separator <= strings.LastIndexAny(url, "/:") {
+        return nil, fmt.Errorf("library source '%s' should be pinned to tag: %s<repository url>@<tag>", location, GIT_PREFIX)
+      }
+      source.Location, source.Ref = GIT_PREFIX + url[:separator], url[separator + 1:]
+    case strings.HasSuffix(location, ".tar.gz") || strings.HasSuffix(location, ".tgz"):
+      source.Type = SOURCE_TARBALL
+    }
+
+    sources = append(sources, source)
+  }
+
+  return
+}
+
+/**
+* Returns library of context at versions recorded in lock file of context: sources are not fetched and lock file
+* is not written (local sources are used in place), source which is not locked yet is an error
+*/
+func ForContext(config map[string]string, contextDir string) (library *Library, err error) {
+  sources, err := ParseSources(config["library-sources"], config["library-path"])
+  if err != nil { return }
+
+  locked, warnings, err := lockedSources(sources, contextDir)
+  if err != nil { return }
+
+  for _, source := range sources {
+    if source.Type == SOURCE_LOCAL {
+      if err = source.Fetch(nil); err != nil { return }
+      continue
+    }
+
+    if !source.useCached(locked[source.Location]) {
+      return nil, fmt.Errorf("library source %s is not locked in %s, run 'devlab create-docker-compose %s' to fetch it",
+        source, filepath.Join(contextDir, LOCK_FILE), filepath.Base(contextDir))
+    }
+  }
+
+  if library, err = LoadSources(sources); err != nil { return }
+  library.Warnings = append(warnings, library.Warnings...)
+
+  return
+}
+
+/**
+* Returns library of context resolving its sources (used by commands generating files of context): sources are
+* fetched at versions recorded in lock file of context, sources which are not locked yet are resolved and added
+* to lock file (update resolves all sources again)
+*/
+func ResolveForContext(config map[string]string, contextDir string, update bool) (library *Library, err error) {
+  sources, err := ParseSources(config["library-sources"], config["library-path"])
+  if err != nil { return }
+
+  locked := make(map[string]*Source)
+  var warnings []string
+  if !update {
+    if locked, warnings, err = lockedSources(sources, contextDir); err != nil { return }
+  }
+
+  for _, source := range sources {
+    if err = source.Fetch(locked[source.Location]); err != nil { return }
+  }
+
+  if err = WriteLock(contextDir, sources); err != nil { return }
+
+  if library, err = LoadSources(sources); err != nil { return }
+  library.Warnings = append(warnings, library.Warnings...)
+
+  return
+}
+
+/**
+* Returns locked sources of context by location, refs of sources are set to locked ones (with warning
+* if they differ)
+*/
+func lockedSources(sources []*Source, contextDir string) (locked map[string]*Source, warnings []string, err error) {
+  lock, err := ReadLock(contextDir)
+  if err != nil { return }
+
+  locked = make(map[string]*Source)
+  for _, source := range lock.Sources {
+    locked[source.Location] = source
+  }
+
+  for _, source := range sources {
+    if lockedSource, ok := locked[source.Location]; ok && lockedSource.Ref != source.Ref {
+      warnings = append(warnings, fmt.Sprintf("library source %s is locked to %s in %s, run 'devlab library update --context %s' to use %s",
+        source.Location, lockedSource.Ref, filepath.Join(contextDir, LOCK_FILE), filepath.Base(contextDir), source.Ref))
+      source.Ref = lockedSource.Ref
+    }
+  }
+
+  return
+}
+
+/**
+* Returns library of latest versions of sources (lock files of contexts are not used)
+*/
+func ForConfig(config map[string]string) (library *Library, err error) {
+  sources, err := ParseSources(config["library-sources"], config["library-path"])
+  if err != nil { return }
+
+  for _, source := range sources {
+    if err = source.Fetch(nil); err != nil { return }
+  }
+
+  return LoadSources(sources)
+}
+
+/**
+* Loads components of sources: component of source listed earlier overrides component with the same name
+* of later source (conflicts are reported as warnings of library)
+*/
+func LoadSources(sources []*Source) (library *Library, err error) {
+  library = &Library{Components: make(map[string]*Component)}
+  if len(sources) > 0 {
+    library.Path = sources[len(sources) - 1].Dir
+  }
+
+  origins := make(map[string]*Source)
+  for _, source := range sources {
+    sourceLibrary, err := Load(source.Dir)
+    if err != nil { return nil, err }
+
+    for _, name := range sourceLibrary.Names() {
+      if origin, isDefined := origins[name]; isDefined {
+        library.Warnings = append(library.Warnings, fmt.Sprintf("component '%s' of %s is overridden by component of %s", name, source, origin))
+        continue
+      }
+      origins[name] = source
+      library.Components[name] = sourceLibrary.Components[name]
+    }
+  }
+
+  return
+}
+
+func (source *Source) String() string {
+  if source.Ref != "" {
+    return source.Location + "@" + source.Ref
+  }
+  return source.Location
+}
+
+/**
+* Fetches source to cache (local source is used in place) and sets its folder and resolved version.
+* Source is fetched at version of locked source if it is set
+*/
+func (source *Source) Fetch(locked *Source) (err error) {
+  switch source.Type {
+  case SOURCE_GIT:
+    err = source.fetchGit(locked)
+  case SOURCE_TARBALL:
+    err = source.fetchTarball(locked)
+  default:
+    source.Dir = source.Location
+    if isExists, _ := files.IsExists(source.Dir); !isExists {
+      err = fmt.Errorf("library source '%s' is not found", source.Location)
+    }
+  }
+
+  if err != nil {
+    return fmt.Errorf("library source %s: %s", source, err)
+  }
+  return
+}
+
+/**
+* Clones tag of repository to cache folder named by its commit (repository is cloned only if locked commit is not cached)
+*/
+func (source *Source) fetchGit(locked *Source) (err error) {
+  if source.useCached(locked) { return }
+  cacheDir := filepath.Join(CachePath(), SOURCE_GIT, hash(source.Location))
+
+  if err = files.CreateDir(cacheDir); err != nil { return }
+  cloneDir, err := os.MkdirTemp(cacheDir, "clone-")
+  if err != nil { return }
+  defer os.RemoveAll(cloneDir)
+
+  url := strings.TrimPrefix(source.Location, GIT_PREFIX)
+  if _, err = exec.Command("git clone --quiet --depth 1 --branch " + util.Quote(source.Ref) + " " + util.Quote(url) + " " + util.Quote(cloneDir) + " 2>&1"); err != nil {
+    return fmt.Errorf("couldn't clone tag '%s' of %s", source.Ref, url)
+  }
+  commit, err := exec.GitCommand(cloneDir, "git rev-parse HEAD")
+  if err != nil { return }
+  source.Resolved = strings.TrimSpace(commit)
+
+  if locked != nil && locked.Resolved != "" && locked.Resolved != source.Resolved {
+    return fmt.Errorf("tag '%s' points to %s, but %s is locked (run 'devlab library update --context <context>' to accept it)", source.Ref, source.Resolved, locked.Resolved)
+  }
+
+  source.Dir = filepath.Join(cacheDir, source.Resolved)
+  if isExists, _ := files.IsExists(source.Dir); !isExists {
+    os.RemoveAll(filepath.Join(cloneDir, ".git"))
+    err = os.Rename(cloneDir, source.Dir)
+  }
+
+  return
+}
+
+/**
+* Downloads (or reads local) tarball and extracts it to cache folder named by its sha256
+*/
+func (source *Source) fetchTarball(locked *Source) (err error) {
+  if source.useCached(locked) { return }
+  cacheDir := filepath.Join(CachePath(), SOURCE_TARBALL)
+
+  data, err := readTarball(source.Location)
+  if err != nil { return }
+  sum := sha256.Sum256(data)
+  source.Resolved = hex.EncodeToString(sum[:])
+
+  if locked != nil && locked.Resolved != "" && locked.Resolved != source.Resolved {
+    return fmt.Errorf("sha256 of tarball is %s, but %s is locked (run 'devlab library update --context <context>' to accept it)", source.Resolved, locked.Resolved)
+  }
+
+  targetDir := filepath.Join(cacheDir, source.Resolved)
+  if isExists, _ := files.IsExists(targetDir); !isExists {
+    if err = files.CreateDir(cacheDir); err != nil { return }
+    extractDir, err := os.MkdirTemp(cacheDir, "extract-")
+    if err != nil { return err }
+    defer os.RemoveAll(extractDir)
+
+    if err = extract(data, extractDir); err != nil { return err }
+    if err = os.Rename(extractDir, targetDir); err != nil { return err }
+  }
+  source.Dir = sourceRoot(targetDir)
+
+  return
+}
+
+/**
+* Sets folder and resolved version of git or tarball source fetched to cache before at version of locked source,
+* returns false if it is not cached
+*/
+func (source *Source) useCached(locked *Source) bool {
+  if locked == nil || locked.Resolved == "" { return false }
+
+  dir := filepath.Join(CachePath(), SOURCE_TARBALL, locked.Resolved)
+  if source.Type == SOURCE_GIT {
+    dir = filepath.Join(CachePath(), SOURCE_GIT, hash(source.Location), locked.Resolved)
+  }
+  if isExists, _ := files.IsExists(dir); !isExists { return false }
+
+  source.Resolved, source.Dir = locked.Resolved, dir
+  if source.Type == SOURCE_TARBALL {
+    source.Dir = sourceRoot(dir)
+  }
+
+  return true
+}
+
+/**
+* Reads lock file of context (lock without sources if there is no lock file)
+*/
+func ReadLock(contextDir string) (lock *Lock, err error) {
+  lock = &Lock{}
+  path := filepath.Join(contextDir, LOCK_FILE)
+  if isExists, _ := files.IsExists(path); !isExists { return }
+
+  data, err := files.ReadTextFile(path)
+  if err != nil { return }
+  if err = yamlv3.Unmarshal([]byte(data), lock); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+
+  return
+}
+
+/**
+* Writes resolved sources to lock file of context
+*/
+func WriteLock(contextDir string, sources []*Source) error {
+  return files.WriteYaml(filepath.Join(contextDir, LOCK_FILE), &Lock{sources})
+}
+
+/**
+* Returns folder of cache of fetched sources
+*/
+func CachePath() string {
+  cacheDir, err := os.UserCacheDir()
+  if err != nil {
+    cacheDir = os.TempDir()
+  }
+
+  return filepath.Join(cacheDir, CACHE_DIR)
+}
+
+func readTarball(location string) (data []byte, err error) {
+  if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+    return os.ReadFile(location)
+  }
+
+  response, err := client.Get(location)
+  if err != nil { return }
+  defer response.Body.Close()
+  if response.StatusCode != http.StatusOK {
+    return nil, fmt.Errorf("couldn't download %s: %s", location, response.Status)
+  }
+
+  return io.ReadAll(response.Body)
+}
+
+/**
+* Extracts gzipped tarball to folder (entries outside of folder are rejected)
+*/
+func extract(data []byte, targetDir string) error {
+  gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+  if err != nil { return err }
+  tarReader := tar.NewReader(gzipReader)
+
+  for {
+    header, err := tarReader.Next()
+    if err == io.EOF { return nil }
+    if err != nil { return err }
+
+    path := filepath.Join(targetDir, header.Name)
+    if path != filepath.Clean(targetDir) && !strings.HasPrefix(path, filepath.Clean(targetDir) + string(os.PathSeparator)) {
+      return fmt.Errorf("tarball entry '%s' is outside of library", header.Name)
+    }
+
+    switch header.Typeflag {
+    case tar.TypeDir:
+      if err = os.MkdirAll(path, 0755); err != nil { return err }
+    case tar.TypeReg:
+      if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil { return err }
+      file, err := os.OpenFile(path, os.O_CREATE | os.O_WRONLY | os.O_TRUNC, os.FileMode(header.Mode) & 0777)
+      if err != nil { return err }
+      _, err = io.Copy(file, tarReader)
+      file.Close()
+      if err != nil { return err }
+    }
+  }
+}
+
+/**
+* Returns root of extracted tarball: its only folder if tarball has one top level folder without components
+*/
+func sourceRoot(dir string) string {
+  entries, err := os.ReadDir(dir)
+  if err == nil && len(entries) == 1 && entries[0].IsDir() && entries[0].Name() != COMPONENTS_DIR {
+    return filepath.Join(dir, entries[0].Name())
+  }
+
+  return dir
+}
+
+func hash(value string) string {
+  sum := sha256.Sum256([]byte(value))
+  return hex.EncodeToString(sum[:])[:16]
+}
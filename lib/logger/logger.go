@@ -1,27 +1,59 @@
 package logger
 
 import (
+  "fmt"
+  "strings"
   colorPrint "github.com/fatih/color" 
 )
 
 const INDENT = "   "
+const REDACTED = "******"
+
+/* Values which are never printed (resolved secrets) */
+var secrets []string
 
 func Header(text string) {
-	colorPrint.Green("\n" + INDENT + " ------------ " + text + " -----------\n\n")
+	printRedacted(colorPrint.Green, "\n" + INDENT + " ------------ " + text + " -----------\n\n")
 }
 
 func Info(textTemplate string, params ...interface{} ) {
-  colorPrint.White(INDENT + textTemplate, params...)
+  printRedacted(colorPrint.White, fmt.Sprintf(INDENT + textTemplate, params...))
 }
 
 func Text(text string) {
-  colorPrint.White(INDENT + text + "\n")
+  printRedacted(colorPrint.White, INDENT + text + "\n")
 }
 
 func Warn(textTemplate string, params ...interface{} ) {
-  colorPrint.Yellow("\n" + INDENT +  "WARNING " + textTemplate, params...)
+  printRedacted(colorPrint.Yellow, fmt.Sprintf("\n" + INDENT +  "WARNING " + textTemplate, params...))
 }
 
 func Debug(textTemplate string, params ...interface{} ) {
-  colorPrint.Magenta("\n" + INDENT +  "DEBUG " + textTemplate, params...)
-}
\ No newline at end of file
+  printRedacted(colorPrint.Magenta, fmt.Sprintf("\n" + INDENT +  "DEBUG " + textTemplate, params...))
+}
+
+/**
+* Prints redacted text with color (new line is added if text has no one)
+*/
+func printRedacted(colorFunc func(format string, params ...interface{}), text string) {
+  colorFunc("%s\n", strings.TrimSuffix(Redact(text), "\n"))
+}
+
+/**
+* Registers secret value, it is replaced with '******' in all output
+*/
+func RegisterSecret(value string) {
+  if value != "" {
+    secrets = append(secrets, value)
+  }
+}
+
+/**
+* Replaces registered secret values in text
+*/
+func Redact(text string) string {
+  for _, secret := range secrets {
+    text = strings.ReplaceAll(text, secret, REDACTED)
+  }
+  return text
+}
@@ -0,0 +1,153 @@
+package logs
+
+import (
+  "encoding/json"
+  "fmt"
+  "regexp"
+  "strings"
+  "time"
+  "devlab/lib/docker"
+)
+
+/* Pause before reconnecting to service whose container is stopped or restarted */
+const RECONNECT_INTERVAL = 2 * time.Second
+
+/* Levels of json logs (pino / bunyan numbers, names are used by winston) */
+var levels = map[string]int{"trace": 10, "debug": 20, "info": 30, "warn": 40, "warning": 40, "error": 50, "fatal": 60}
+
+/**
+* Line of service log
+*/
+type Line struct {
+  Service string
+  Time time.Time
+  Text string
+}
+
+/**
+* Filter of log lines: lines matching regular expression and json lines with level not lower than minimal one
+* (lines which are not json or have no level are not filtered by level)
+*/
+type Filter struct {
+  Grep *regexp.Regexp
+  /* 0 means all levels */
+  Level int
+}
+
+/**
+* Returns minimal level of filter by its name (trace, debug, info, warn, error, fatal)
+*/
+func ParseLevel(name string) (int, error) {
+  level, ok := levels[strings.ToLower(name)]
+  if !ok {
+    return 0, fmt.Errorf("unknown log level '%s', supported levels: trace, debug, info, warn, error, fatal", name)
+  }
+
+  return level, nil
+}
+
+/**
+* Parses --since value: duration before now (e.g. 10m) or time in RFC3339 format
+*/
+func ParseSince(value string) (time.Time, error) {
+  if value == "" {
+    return time.Time{}, nil
+  }
+
+  if duration, err := time.ParseDuration(value); err == nil {
+    return time.Now().Add(-duration), nil
+  }
+
+  since, err := time.Parse(time.RFC3339, value)
+  if err != nil {
+    return since, fmt.Errorf("since '%s' should be duration (e.g. 10m) or time in RFC3339 format", value)
+  }
+
+  return since, nil
+}
+
+/**
+* Checks if text of log line passes filter
+*/
+func (filter *Filter) Match(text string) bool {
+  if filter.Grep != nil && !filter.Grep.MatchString(text) {
+    return false
+  }
+
+  if filter.Level > 0 && strings.HasPrefix(strings.TrimSpace(text), "{") {
+    var entry struct {
+      Level interface{} `json:"level"`
+    }
+    if err := json.Unmarshal([]byte(text), &entry); err == nil {
+      switch level := entry.Level.(type) {
+      case float64:
+        return int(level) >= filter.Level
+      case string:
+        if number, ok := levels[strings.ToLower(level)]; ok {
+          return number >= filter.Level
+        }
+      }
+    }
+  }
+
+  return true
+}
+
+/**
+* Streams log of service of docker compose project: when container is stopped or restarted stream is reconnected
+* (lines which were already handled are skipped). Returns if follow is false or if containers of project could not be listed
+*/
+func Follow(client docker.Client, project string, serviceName string, since time.Time, follow bool, handle func(Line)) error {
+  lastTime := since
+  isWaitingReported := false
+
+  for {
+    /* missing container is awaited, but docker which can't be reached (no socket, no permission) is an error */
+    container, err := serviceContainer(client, project, serviceName)
+    if err != nil { return err }
+
+    if container != nil {
+      isWaitingReported = false
+
+      stream, err := client.Logs(*container, docker.LogsOptions{Follow: follow, Since: lastTime})
+      if err == nil {
+        err = docker.ReadLogs(stream, container.Tty, func(text string) {
+          timestamp, text, _ := strings.Cut(text, " ")
+          lineTime, parseErr := time.Parse(time.RFC3339Nano, timestamp)
+          if parseErr == nil {
+            /* since is inclusive, so last handled line could be sent again after reconnection */
+            if !lineTime.After(lastTime) && !lastTime.IsZero() { return }
+            lastTime = lineTime
+          }
+          handle(Line{serviceName, lineTime, text})
+        })
+        stream.Close()
+      }
+      if err != nil && !follow { return err }
+    } else if !isWaitingReported && follow {
+      handle(Line{serviceName, time.Now(), "(waiting for container)"})
+      isWaitingReported = true
+    }
+
+    if !follow { return nil }
+    time.Sleep(RECONNECT_INTERVAL)
+  }
+}
+
+/**
+* Returns container of service (running one if there are several ones, nil if there is no container)
+*/
+func serviceContainer(client docker.Client, project string, serviceName string) (*docker.Container, error) {
+  containers, err := client.Containers(project)
+  if err != nil { return nil, err }
+
+  var found *docker.Container
+  for i := range containers {
+    if containers[i].Service != serviceName { continue }
+    if found == nil || containers[i].State == "running" {
+      found = &containers[i]
+    }
+  }
+
+  return found, nil
+}
@@ -0,0 +1,163 @@
+package ports
+
+import (
+  "fmt"
+  "net"
+  "os"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/state"
+)
+
+/**
+* Range of host ports devlab allocates for contexts ('ports-range' config)
+*/
+type Range struct {
+  From int
+  To int
+}
+
+/**
+* Parses range of ports ('20000-29999')
+*/
+func ParseRange(value string) (portsRange Range, err error) {
+  from, to, _ := strings.Cut(value, "-")
+  portsRange.From, err = strconv.Atoi(strings.TrimSpace(from))
+  if err == nil {
+    portsRange.To, err = strconv.Atoi(strings.TrimSpace(to))
+  }
+  if err != nil || portsRange.From < 1 || portsRange.To > 65535 || portsRange.From > portsRange.To {
+    return portsRange, fmt.Errorf("'%s' is not valid ports range (expected e.g. 20000-29999)", value)
+  }
+
+  return
+}
+
+/**
+* Checks if port is not in use on host
+*/
+func IsFree(port int) bool {
+  listener, err := net.Listen("tcp", ":" + strconv.Itoa(port))
+  if err != nil {
+    return false
+  }
+  listener.Close()
+
+  return true
+}
+
+/**
+* Returns host ports allocated by other contexts: port => context name
+*/
+func UsedByOtherContexts(contextsPath string, contextName string) (usedPorts map[int]string, err error) {
+  usedPorts = make(map[int]string)
+
+  contextDirs, err := filepath.Glob(contextsPath + "/*")
+  if err != nil { return }
+
+  for _, contextDir := range contextDirs {
+    otherContextName := filepath.Base(contextDir)
+    if otherContextName == contextName { continue }
+    if info, err := os.Stat(contextDir); err != nil || !info.IsDir() { continue }
+
+    otherState, err := state.Load(contextDir)
+    if err != nil { return usedPorts, err }
+
+    for _, servicePorts := range otherState.Ports {
+      for _, hostPort := range servicePorts {
+        usedPorts[hostPort] = otherContextName
+      }
+    }
+  }
+
+  return
+}
+
+/**
+* Allocates host ports from range for all published ports of services and rewrites them to 'host:container'.
+* Ports already allocated for the context (in its state) are kept, so they are stable between runs;
+* new ports are taken from the range skipping ports of other contexts and ports in use on host
+*/
+func Allocate(dockerComposeFiles []*DockerComposeFileBuilder.DockerComposeFile, contextState *state.State, portsRange Range, usedPorts map[int]string) (warnings []string, err error) {
+  taken := make(map[int]bool)
+  for port := range usedPorts {
+    taken[port] = true
+  }
+  for _, servicePorts := range contextState.Ports {
+    for _, hostPort := range servicePorts {
+      taken[hostPort] = true
+    }
+  }
+
+  nextPort := portsRange.From
+  allocate := func() (int, error) {
+    for ; nextPort <= portsRange.To; nextPort++ {
+      if !taken[nextPort] && IsFree(nextPort) {
+        taken[nextPort] = true
+        return nextPort, nil
+      }
+    }
+    return 0, fmt.Errorf("there are no free ports in range %d-%d", portsRange.From, portsRange.To)
+  }
+
+  allocatedPorts := make(map[string]map[string]int)
+  for _, dockerComposeData := range dockerComposeFiles {
+    serviceNames := make([]string, 0, len(dockerComposeData.Services))
+    for serviceName := range dockerComposeData.Services {
+      serviceNames = append(serviceNames, serviceName)
+    }
+    sort.Strings(serviceNames)
+
+    for _, serviceName := range serviceNames {
+      service := dockerComposeData.Services[serviceName]
+      for i, port := range service.Ports {
+        containerPort, ok := ContainerPort(port)
+        if !ok {
+          warnings = append(warnings, fmt.Sprintf("service '%s': port '%s' is kept as is", serviceName, port))
+          continue
+        }
+
+        hostPort, isAllocated := contextState.Ports[serviceName][containerPort]
+        if !isAllocated {
+          if hostPort, err = allocate(); err != nil { return }
+        }
+
+        if allocatedPorts[serviceName] == nil {
+          allocatedPorts[serviceName] = make(map[string]int)
+        }
+        allocatedPorts[serviceName][containerPort] = hostPort
+
+        /* host ip of binding is kept */
+        hostIp := ""
+        if parts := strings.Split(port, ":"); len(parts) == 3 {
+          hostIp = parts[0] + ":"
+        }
+        service.Ports[i] = hostIp + strconv.Itoa(hostPort) + ":" + containerPort
+      }
+    }
+  }
+
+  /* ports of removed services are released */
+  contextState.Ports = allocatedPorts
+
+  return
+}
+
+/**
+* Returns container port (with protocol if it is set) of '[ip:][host:]container[/protocol]' port definition,
+* port ranges and variables are not supported
+*/
+func ContainerPort(port string) (string, bool) {
+  parts := strings.Split(port, ":")
+  containerPort := parts[len(parts) - 1]
+
+  number, _, _ := strings.Cut(containerPort, "/")
+  if _, err := strconv.Atoi(number); err != nil {
+    return "", false
+  }
+
+  return containerPort, true
+}
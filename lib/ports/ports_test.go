@@ -0,0 +1,161 @@
+package ports
+
+import (
+  "net"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strconv"
+  "testing"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/state"
+)
+
+func TestParseRange(t *testing.T) {
+  tests := []struct {
+    value string
+    expected Range
+    isError bool
+  }{
+    {value: "20000-29999", expected: Range{From: 20000, To: 29999}},
+    {value: " 20000 - 20000 ", expected: Range{From: 20000, To: 20000}},
+    {value: "1-65535", expected: Range{From: 1, To: 65535}},
+    {value: "", isError: true},
+    {value: "20000", isError: true},
+    {value: "20000-", isError: true},
+    {value: "a-b", isError: true},
+    {value: "0-100", isError: true},
+    {value: "60000-70000", isError: true},
+    {value: "29999-20000", isError: true},
+  }
+
+  for _, test := range tests {
+    t.Run(test.value, func(t *testing.T) {
+      portsRange, err := ParseRange(test.value)
+      if test.isError {
+        if err == nil {
+          t.Errorf("expected error, got %+v", portsRange)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      if portsRange != test.expected {
+        t.Errorf("expected %+v, got %+v", test.expected, portsRange)
+      }
+    })
+  }
+}
+
+func TestContainerPort(t *testing.T) {
+  tests := []struct {
+    port string
+    expected string
+    isSupported bool
+  }{
+    {port: "8080", expected: "8080", isSupported: true},
+    {port: "20001:8080", expected: "8080", isSupported: true},
+    {port: "127.0.0.1:20001:8080/udp", expected: "8080/udp", isSupported: true},
+    {port: "9000-9001:9000-9001"},
+    {port: "${API_PORT}"},
+  }
+
+  for _, test := range tests {
+    t.Run(test.port, func(t *testing.T) {
+      containerPort, isSupported := ContainerPort(test.port)
+      if containerPort != test.expected || isSupported != test.isSupported {
+        t.Errorf("expected '%s' %t, got '%s' %t", test.expected, test.isSupported, containerPort, isSupported)
+      }
+    })
+  }
+}
+
+/**
+* Ports of state.yml of other contexts and ports in use on host are skipped, ports allocated for the context are kept
+*/
+func TestAllocate(t *testing.T) {
+  /* port in use on host is the first port of range */
+  listener, err := net.Listen("tcp", ":0")
+  if err != nil { t.Fatal(err) }
+  defer listener.Close()
+  from := listener.Addr().(*net.TCPAddr).Port
+  if from + 20 > 65535 {
+    t.Skipf("port %d is too high for range", from)
+  }
+  portsRange := Range{From: from, To: from + 20}
+
+  contextsPath := t.TempDir()
+  writeState(t, contextsPath, "bar", map[string]map[string]int{"api": {"8080": from + 1}, "db": {"5432": from + 2}})
+  writeState(t, contextsPath, "foo", map[string]map[string]int{"db": {"5432": from + 10}, "removed": {"80": from + 11}})
+  if err := os.WriteFile(filepath.Join(contextsPath, "README.md"), []byte("not a context"), 0644); err != nil { t.Fatal(err) }
+
+  usedPorts, err := UsedByOtherContexts(contextsPath, "foo")
+  if err != nil { t.Fatal(err) }
+  if expected := map[int]string{from + 1: "bar", from + 2: "bar"}; !reflect.DeepEqual(usedPorts, expected) {
+    t.Fatalf("expected ports of other contexts %v, got %v", expected, usedPorts)
+  }
+
+  contextState, err := state.Load(filepath.Join(contextsPath, "foo"))
+  if err != nil { t.Fatal(err) }
+
+  dockerComposeData, err := DockerComposeFileBuilder.Parse(`services:
+  db:
+    image: postgres
+    ports:
+      - "5432"
+  api:
+    image: api
+    ports:
+      - 127.0.0.1::8080
+      - "9229"
+      - 9000-9001:9000-9001
+`)
+  if err != nil { t.Fatal(err) }
+
+  warnings, err := Allocate([]*DockerComposeFileBuilder.DockerComposeFile{dockerComposeData}, contextState, portsRange, usedPorts)
+  if err != nil { t.Fatal(err) }
+
+  /* services are allocated in order of names: api gets the first free ports (ports of state of context are taken) */
+  taken := map[int]bool{from + 10: true, from + 11: true}
+  apiPort := nextFree(from + 3, taken)
+  debugPort := nextFree(apiPort + 1, taken)
+
+  expectedPorts := []string{"127.0.0.1:" + strconv.Itoa(apiPort) + ":8080", strconv.Itoa(debugPort) + ":9229", "9000-9001:9000-9001"}
+  if ports := dockerComposeData.Services["api"].Ports; !reflect.DeepEqual(ports, expectedPorts) {
+    t.Errorf("expected ports of api %v, got %v", expectedPorts, ports)
+  }
+  if ports := dockerComposeData.Services["db"].Ports; !reflect.DeepEqual(ports, []string{strconv.Itoa(from + 10) + ":5432"}) {
+    t.Errorf("allocated port of db should be kept, got %v", ports)
+  }
+  if expected := []string{"service 'api': port '9000-9001:9000-9001' is kept as is"}; !reflect.DeepEqual(warnings, expected) {
+    t.Errorf("expected warnings %v, got %v", expected, warnings)
+  }
+
+  expectedState := map[string]map[string]int{"api": {"8080": apiPort, "9229": debugPort}, "db": {"5432": from + 10}}
+  if !reflect.DeepEqual(contextState.Ports, expectedState) {
+    t.Errorf("expected state %v (ports of removed services are released), got %v", expectedState, contextState.Ports)
+  }
+
+  /* range without free ports */
+  contextState = &state.State{Ports: make(map[string]map[string]int)}
+  dockerComposeData, _ = DockerComposeFileBuilder.Parse("services:\n  api:\n    image: api\n    ports:\n      - \"8080\"\n")
+  if _, err = Allocate([]*DockerComposeFileBuilder.DockerComposeFile{dockerComposeData}, contextState, Range{From: from + 1, To: from + 2}, usedPorts); err == nil {
+    t.Errorf("expected error when all ports of range are used by other contexts")
+  }
+}
+
+func writeState(t *testing.T, contextsPath string, contextName string, ports map[string]map[string]int) {
+  contextDir := filepath.Join(contextsPath, contextName)
+  if err := os.MkdirAll(contextDir, 0755); err != nil { t.Fatal(err) }
+  if err := (&state.State{Ports: ports}).Save(contextDir); err != nil { t.Fatal(err) }
+}
+
+/**
+* Returns the first port starting from port which is free on host and is not taken
+*/
+func nextFree(port int, taken map[int]bool) int {
+  for ; taken[port] || !IsFree(port); port++ {}
+  return port
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+  "bytes"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strings"
+  "devlab/lib/secrets"
+)
+
+/* Sections of settings.yml with databases (<db>: {owner, migrations, seeds}) and roles (<role>: {password}) */
+const DATABASES_SECTION = "postgres-databases"
+const ROLES_SECTION = "postgres-roles"
+
+/* Service of postgres, sql is run with psql inside its container */
+const SERVICE = "postgres"
+const SUPERUSER = "postgres"
+
+/* Table of database with applied migrations */
+const MIGRATIONS_TABLE = "devlab_migrations"
+
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+type Role struct {
+  Name string
+  /* resolved password (could be secret reference in settings.yml) */
+  Password string
+}
+
+/**
+* Database of context: migrations are applied once (they are recorded in devlab_migrations table),
+* seeds are loaded into new and reset database. Both are folders with *.sql files run in order of names
+*/
+type Database struct {
+  Name string
+  Owner string
+  Migrations string
+  Seeds string
+}
+
+/**
+* Runs command inside container of postgres with stdin and stdout connected to reader and writer
+*/
+type Stream func(command string, stdin io.Reader, stdout io.Writer) error
+
+/**
+* Returns roles and databases declared in settings.yml of context, folders of migrations and seeds
+* are relative to context folder
+*/
+func Declared(context map[string]map[string]map[string]string, contextDir string) (roles map[string]*Role, databases map[string]*Database, err error) {
+  roles = make(map[string]*Role)
+  for name, params := range context[ROLES_SECTION] {
+    if !namePattern.MatchString(name) {
+      return nil, nil, fmt.Errorf("postgres role '%s': name should contain lower case letters, digits and '_'", name)
+    }
+
+    role := &Role{Name: name}
+    if role.Password, err = secrets.Resolve(params["password"]); err != nil {
+      return nil, nil, fmt.Errorf("postgres role '%s': %s", name, err)
+    }
+    roles[name] = role
+  }
+
+  databases = make(map[string]*Database)
+  for name, params := range context[DATABASES_SECTION] {
+    if !namePattern.MatchString(name) {
+      return nil, nil, fmt.Errorf("postgres database '%s': name should contain lower case letters, digits and '_'", name)
+    }
+
+    database := &Database{Name: name, Owner: params["owner"]}
+    if database.Owner == "" {
+      database.Owner = SUPERUSER
+    } else if _, ok := roles[database.Owner]; !ok && database.Owner != SUPERUSER {
+      return nil, nil, fmt.Errorf("postgres database '%s': owner '%s' is not declared in %s", name, database.Owner, ROLES_SECTION)
+    }
+
+    for key, target := range map[string]*string{"migrations": &database.Migrations, "seeds": &database.Seeds} {
+      if params[key] == "" { continue }
+      *target = filepath.Join(contextDir, params[key])
+      if isDir(*target) { continue }
+      return nil, nil, fmt.Errorf("postgres database '%s': %s folder '%s' is not found", name, key, *target)
+    }
+
+    databases[name] = database
+  }
+
+  return
+}
+
+/**
+* Returns sorted names of databases
+*/
+func Names(databases map[string]*Database) []string {
+  names := make([]string, 0, len(databases))
+  for name := range databases {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  return names
+}
+
+/**
+* Client running sql with psql of postgres container
+*/
+type Client struct {
+  Stream Stream
+}
+
+/**
+* Creates missing roles (passwords of existing roles are updated) and databases, new databases
+* are migrated and seeded. Returns names of created databases
+*/
+func (client *Client) Provision(roles map[string]*Role, databases map[string]*Database) (created []string, err error) {
+  roleNames := make([]string, 0, len(roles))
+  for name := range roles {
+    roleNames = append(roleNames, name)
+  }
+  sort.Strings(roleNames)
+
+  for _, name := range roleNames {
+    password := literal(roles[name].Password)
+    sql := fmt.Sprintf(`DO $$ BEGIN
+  IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN CREATE ROLE %s LOGIN PASSWORD %s;
+  ELSE ALTER ROLE %s LOGIN PASSWORD %s;
+  END IF;
+END $$;`, literal(name), identifier(name), password, identifier(name), password)
+    if _, err = client.Query(SUPERUSER, sql); err != nil {
+      return nil, fmt.Errorf("postgres role '%s': %s", name, err)
+    }
+  }
+
+  for _, name := range Names(databases) {
+    out, err := client.Query(SUPERUSER, fmt.Sprintf("SELECT 1 FROM pg_database WHERE datname = %s;", literal(name)))
+    if err != nil { return nil, err }
+    if strings.TrimSpace(out) == "1" { continue }
+
+    if err = client.create(databases[name]); err != nil { return nil, err }
+    created = append(created, name)
+  }
+
+  return
+}
+
+/**
+* Drops database (its connections are terminated) and creates it again with migrations and seeds
+*/
+func (client *Client) Reset(database *Database) (err error) {
+  sql := fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = %s AND pid <> pg_backend_pid();\nDROP DATABASE IF EXISTS %s;",
+    literal(database.Name), identifier(database.Name))
+  if _, err = client.Query(SUPERUSER, sql); err != nil { return }
+
+  return client.create(database)
+}
+
+/**
+* Applies migrations which are not applied yet, returns names of applied ones
+*/
+func (client *Client) Migrate(database *Database) (applied []string, err error) {
+  if database.Migrations == "" { return }
+
+  sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now());\nSELECT name FROM %s;",
+    MIGRATIONS_TABLE, MIGRATIONS_TABLE)
+  out, err := client.Query(database.Name, sql)
+  if err != nil { return }
+
+  isApplied := make(map[string]bool)
+  for _, name := range strings.Split(out, "\n") {
+    isApplied[strings.TrimSpace(name)] = true
+  }
+
+  paths, err := sqlFiles(database.Migrations)
+  if err != nil { return }
+  for _, path := range paths {
+    name := filepath.Base(path)
+    if isApplied[name] { continue }
+
+    content, err := os.ReadFile(path)
+    if err != nil { return applied, err }
+
+    /* migration and its record are applied in one transaction */
+    sql := "BEGIN;\n" + string(content) + fmt.Sprintf("\nINSERT INTO %s (name) VALUES (%s);\nCOMMIT;", MIGRATIONS_TABLE, literal(name))
+    if _, err = client.Query(database.Name, sql); err != nil {
+      return applied, fmt.Errorf("migration %s: %s", path, err)
+    }
+    applied = append(applied, name)
+  }
+
+  return
+}
+
+/**
+* Runs seeds of database
+*/
+func (client *Client) Seed(database *Database) error {
+  if database.Seeds == "" { return nil }
+
+  paths, err := sqlFiles(database.Seeds)
+  if err != nil { return err }
+  for _, path := range paths {
+    file, err := os.Open(path)
+    if err != nil { return err }
+    err = client.Stream(psql(database.Name, false), file, io.Discard)
+    file.Close()
+    if err != nil {
+      return fmt.Errorf("seed %s: %s", path, err)
+    }
+  }
+
+  return nil
+}
+
+/**
+* Writes sql dump of database (it drops existing objects when it is restored)
+*/
+func (client *Client) Dump(database *Database, output io.Writer) error {
+  return client.Stream(fmt.Sprintf("pg_dump -U %s --clean --if-exists %s", SUPERUSER, database.Name), nil, output)
+}
+
+/**
+* Runs sql dump in database
+*/
+func (client *Client) Restore(database *Database, input io.Reader) error {
+  return client.Stream(psql(database.Name, false), input, io.Discard)
+}
+
+/**
+* Runs sql in database as superuser, returns unaligned output without headers
+*/
+func (client *Client) Query(databaseName string, sql string) (string, error) {
+  var out bytes.Buffer
+  err := client.Stream(psql(databaseName, true), strings.NewReader(sql), &out)
+
+  return out.String(), err
+}
+
+func (client *Client) create(database *Database) (err error) {
+  if _, err = client.Query(SUPERUSER, fmt.Sprintf("CREATE DATABASE %s OWNER %s;", identifier(database.Name), identifier(database.Owner))); err != nil { return }
+  if _, err = client.Migrate(database); err != nil { return }
+
+  return client.Seed(database)
+}
+
+func psql(databaseName string, isQuiet bool) string {
+  command := fmt.Sprintf("psql -v ON_ERROR_STOP=1 -U %s -d %s", SUPERUSER, databaseName)
+  if isQuiet {
+    command += " -q -At"
+  }
+  return command
+}
+
+/**
+* Returns *.sql files of folder sorted by names
+*/
+func sqlFiles(dir string) ([]string, error) {
+  paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+  sort.Strings(paths)
+
+  return paths, err
+}
+
+func identifier(name string) string {
+  return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func literal(value string) string {
+  return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func isDir(path string) bool {
+  info, err := os.Stat(path)
+  return err == nil && info.IsDir()
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+  "fmt"
+  "io"
+  "time"
+  "devlab/lib/deploy"
+  "devlab/lib/logger"
+)
+
+/* How long to wait for postgres before databases are provisioned */
+const POSTGRES_TIMEOUT = 2 * time.Minute
+
+/**
+* Creates roles and databases declared in settings.yml of context once postgres is healthy, applies
+* new migrations (used by 'devlab up')
+*/
+func Provision(backend deploy.Backend, target *deploy.Target) error {
+  if len(target.Context[DATABASES_SECTION]) == 0 && len(target.Context[ROLES_SECTION]) == 0 { return nil }
+
+  roles, databases, err := Declared(target.Context, target.ContextDir)
+  if err != nil { return err }
+
+  if err = backend.Wait(POSTGRES_TIMEOUT, SERVICE); err != nil { return err }
+
+  client := NewClient(backend)
+  created, err := client.Provision(roles, databases)
+  if err != nil { return err }
+  for _, name := range created {
+    logger.Info("Database '%s' is created\n", name)
+  }
+
+  for _, name := range Names(databases) {
+    applied, err := client.Migrate(databases[name])
+    if err != nil {
+      return fmt.Errorf("database '%s': %s", name, err)
+    }
+    for _, migration := range applied {
+      logger.Info("%s: migration %s is applied\n", name, migration)
+    }
+  }
+
+  return nil
+}
+
+/**
+* Returns client running psql inside container of postgres of backend
+*/
+func NewClient(backend deploy.Backend) *Client {
+  return &Client{Stream: func(command string, stdin io.Reader, stdout io.Writer) error {
+    return backend.Stream(SERVICE, command, stdin, stdout)
+  }}
+}
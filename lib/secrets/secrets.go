@@ -0,0 +1,257 @@
+package secrets
+
+import (
+  "crypto/aes"
+  "crypto/cipher"
+  "crypto/pbkdf2"
+  "crypto/rand"
+  "crypto/sha256"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "devlab/lib/config"
+  "devlab/lib/files"
+  "devlab/lib/logger"
+)
+
+/* Settings value 'secret://<name>' is a reference to secret */
+const REFERENCE_PREFIX = "secret://"
+
+/* Environment variable with secret value overrides store (DEVLAB_SECRET_<NAME>) */
+const ENV_PREFIX = "DEVLAB_SECRET_"
+
+/* Passphrase to derive store key from (instead of key file) */
+const PASSPHRASE_ENV = "DEVLAB_SECRETS_PASSPHRASE"
+
+const STORE_FILE = "secrets.enc"
+const KEY_FILE = "secrets.key"
+
+const KDF_KEY_FILE = "key-file"
+const KDF_PBKDF2 = "pbkdf2-sha256"
+const PBKDF2_ITERATIONS = 600000
+
+/* Values of secrets resolved by this run */
+var resolved = make(map[string]bool)
+
+/**
+* Encrypted store of secrets (AES-256-GCM). Key is read from key file (created on first write) or derived
+* from passphrase in DEVLAB_SECRETS_PASSPHRASE
+*/
+type encryptedStore struct {
+  Kdf string `json:"kdf"`
+  Salt []byte `json:"salt,omitempty"`
+  Nonce []byte `json:"nonce"`
+  Data []byte `json:"data"`
+}
+
+/**
+* Checks if settings value is reference to secret
+*/
+func IsReference(value string) bool {
+  return strings.HasPrefix(value, REFERENCE_PREFIX)
+}
+
+/**
+* Returns value of settings value: secret references are resolved from environment or store
+* (resolved secrets are redacted in logs), other values are returned as is
+*/
+func Resolve(value string) (string, error) {
+  if !IsReference(value) {
+    return value, nil
+  }
+
+  name := strings.TrimPrefix(value, REFERENCE_PREFIX)
+  secret, isSet := os.LookupEnv(EnvName(name))
+  if !isSet {
+    store, err := Load()
+    if err != nil { return "", err }
+
+    secret, isSet = store[name]
+  }
+
+  if !isSet {
+    return "", fmt.Errorf("secret '%s' is not set: run 'devlab secret set %s' or set %s", name, name, EnvName(name))
+  }
+
+  logger.RegisterSecret(secret)
+  resolved[secret] = true
+  return secret, nil
+}
+
+/**
+* Checks if value is (or contains) a secret resolved by this run
+*/
+func IsSecret(value string) bool {
+  for secret := range resolved {
+    if secret != "" && strings.Contains(value, secret) {
+      return true
+    }
+  }
+
+  return false
+}
+
+/**
+* Resolves secret references in values of map, returns names of keys with secrets
+*/
+func ResolveAll(values map[string]string) (secretKeys []string, err error) {
+  for key, value := range values {
+    if !IsReference(value) { continue }
+
+    if values[key], err = Resolve(value); err != nil {
+      return
+    }
+    secretKeys = append(secretKeys, key)
+  }
+  sort.Strings(secretKeys)
+
+  return
+}
+
+/**
+* Returns name of environment variable with secret ('postgres-password' => 'DEVLAB_SECRET_POSTGRES_PASSWORD')
+*/
+func EnvName(name string) string {
+  return ENV_PREFIX + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+/**
+* Returns path to store (it is kept next to global config, out of project folder)
+*/
+func StorePath() string {
+  return filepath.Join(filepath.Dir(config.GlobalConfigPath()), STORE_FILE)
+}
+
+/**
+* Loads and decrypts secrets store (empty if there is no store)
+*/
+func Load() (secrets map[string]string, err error) {
+  secrets = make(map[string]string)
+
+  isExists, _ := files.IsExists(StorePath())
+  if !isExists { return }
+
+  data, err := os.ReadFile(StorePath())
+  if err != nil { return }
+
+  var store encryptedStore
+  if err = json.Unmarshal(data, &store); err != nil {
+    return secrets, fmt.Errorf("%s: %s", StorePath(), err)
+  }
+
+  gcm, err := newCipher(store.Kdf, store.Salt, false)
+  if err != nil { return }
+
+  plain, err := gcm.Open(nil, store.Nonce, store.Data, nil)
+  if err != nil {
+    return secrets, fmt.Errorf("secrets store %s couldn't be decrypted (wrong key file or passphrase)", StorePath())
+  }
+
+  err = json.Unmarshal(plain, &secrets)
+  return
+}
+
+/**
+* Encrypts and saves secrets store
+*/
+func Save(secrets map[string]string) (err error) {
+  if err = files.CreateDir(filepath.Dir(StorePath())); err != nil { return }
+
+  store := encryptedStore{Kdf: KDF_KEY_FILE}
+  if os.Getenv(PASSPHRASE_ENV) != "" {
+    store.Kdf, store.Salt = KDF_PBKDF2, make([]byte, 16)
+    if _, err = rand.Read(store.Salt); err != nil { return }
+  }
+
+  gcm, err := newCipher(store.Kdf, store.Salt, true)
+  if err != nil { return }
+
+  store.Nonce = make([]byte, gcm.NonceSize())
+  if _, err = rand.Read(store.Nonce); err != nil { return }
+
+  plain, err := json.Marshal(secrets)
+  if err != nil { return }
+  store.Data = gcm.Seal(nil, store.Nonce, plain, nil)
+
+  data, err := json.Marshal(store)
+  if err != nil { return }
+
+  return files.WriteFileAtomicWithMode(StorePath(), string(data), 0600)
+}
+
+/**
+* Sets secret in store
+*/
+func Set(name string, value string) error {
+  secrets, err := Load()
+  if err != nil { return err }
+
+  secrets[name] = value
+  return Save(secrets)
+}
+
+/**
+* Deletes secret from store
+*/
+func Delete(name string) error {
+  secrets, err := Load()
+  if err != nil { return err }
+
+  if _, ok := secrets[name]; !ok {
+    return fmt.Errorf("secret '%s' is not found", name)
+  }
+
+  delete(secrets, name)
+  return Save(secrets)
+}
+
+func newCipher(kdf string, salt []byte, isCreateKey bool) (gcm cipher.AEAD, err error) {
+  var key []byte
+
+  switch kdf {
+  case KDF_PBKDF2:
+    passphrase := os.Getenv(PASSPHRASE_ENV)
+    if passphrase == "" {
+      return nil, fmt.Errorf("secrets store is encrypted with passphrase, set %s", PASSPHRASE_ENV)
+    }
+    key, err = pbkdf2.Key(sha256.New, passphrase, salt, PBKDF2_ITERATIONS, 32)
+  case KDF_KEY_FILE:
+    key, err = readKeyFile(isCreateKey)
+  default:
+    err = fmt.Errorf("unknown key derivation '%s' of secrets store", kdf)
+  }
+  if err != nil { return }
+
+  block, err := aes.NewCipher(key)
+  if err != nil { return }
+
+  return cipher.NewGCM(block)
+}
+
+/**
+* Reads key of store from key file, creates key file with random key if it is needed
+*/
+func readKeyFile(isCreateKey bool) (key []byte, err error) {
+  keyPath := filepath.Join(filepath.Dir(StorePath()), KEY_FILE)
+
+  isExists, _ := files.IsExists(keyPath)
+  if !isExists {
+    if !isCreateKey {
+      return nil, fmt.Errorf("key file %s of secrets store is not found", keyPath)
+    }
+
+    key = make([]byte, 32)
+    if _, err = rand.Read(key); err != nil { return }
+    err = os.WriteFile(keyPath, []byte(hex.EncodeToString(key) + "\n"), 0600)
+    return
+  }
+
+  data, err := os.ReadFile(keyPath)
+  if err != nil { return }
+
+  return hex.DecodeString(strings.TrimSpace(string(data)))
+}
@@ -2,7 +2,9 @@ package services
 
 import (
   "bufio"
+  "fmt"
   "os"
+  "strings"
   "time"
   "strconv"
   "devlab/lib/exec"
@@ -160,4 +162,44 @@ func CommitChanges(contextServicesDir string, serviceName string) {
 
     exec.GitCommand(serviceDir, "git stash" )
   }
-}
\ No newline at end of file
+}
+/**
+* State of service repository
+*/
+type GitState struct {
+  Branch string `json:"branch"`
+  Commit string `json:"commit"`
+  /* commits which are not pushed to upstream / not pulled from upstream */
+  Ahead int `json:"ahead"`
+  Behind int `json:"behind"`
+  /* there are not commited changes */
+  Dirty bool `json:"dirty"`
+}
+
+/**
+* Returns state of service repository (nil if service is not cloned)
+*/
+func GitStatus(contextServicesDir string, serviceName string) (state *GitState, err error) {
+  serviceDir, _ := files.AbsolutePath(contextServicesDir + "/" + serviceName)
+  isServiceDirExists, _ := files.IsExists(serviceDir + "/.git")
+  if !isServiceDirExists { return }
+
+  out, err := exec.GitCommand(serviceDir, "git status --porcelain=v2 --branch")
+  if err != nil { return }
+
+  state = &GitState{}
+  for _, line := range strings.Split(out, "\n") {
+    switch {
+    case strings.HasPrefix(line, "# branch.head "):
+      state.Branch = strings.TrimPrefix(line, "# branch.head ")
+    case strings.HasPrefix(line, "# branch.oid "):
+      state.Commit = strings.TrimPrefix(line, "# branch.oid ")
+    case strings.HasPrefix(line, "# branch.ab "):
+      fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &state.Ahead, &state.Behind)
+    case line != "" && !strings.HasPrefix(line, "#"):
+      state.Dirty = true
+    }
+  }
+
+  return
+}
@@ -0,0 +1,365 @@
+package snapshot
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strings"
+  "time"
+  "devlab/lib/docker-compose-file-builder"
+  "devlab/lib/exec"
+  "devlab/lib/files"
+  "devlab/lib/services"
+  yamlv3 "gopkg.in/yaml.v3"
+  "devlab/lib/util"
+)
+
+/* Folder of context with snapshots, every snapshot is a folder with archives of volumes and metadata */
+const SNAPSHOTS_DIR = "snapshots"
+const METADATA_FILE = "snapshot.yml"
+
+/* Image of helper container which archives and extracts volumes (files of volumes are owned by users of containers) */
+const HELPER_IMAGE = "alpine:3"
+
+/* Format of name of snapshot which is created without name */
+const NAME_FORMAT = "20060102-150405"
+
+const VOLUME_TYPE_NAMED = "volume"
+const VOLUME_TYPE_BIND = "bind"
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+/**
+* Data volume of system service: named docker volume of compose project or writable bind-mounted host folder
+*/
+type Volume struct {
+  Service string `yaml:"service"`
+  Type string `yaml:"type"`
+  /* name of volume in compose file (folder name for bind mounts) */
+  Name string `yaml:"name"`
+  /* name of docker volume or absolute path of host folder */
+  Source string `yaml:"source"`
+  Archive string `yaml:"archive"`
+}
+
+/**
+* Git revision of application service at the time snapshot is taken
+*/
+type Revision struct {
+  Branch string `yaml:"branch"`
+  Commit string `yaml:"commit"`
+  /* there were not commited changes */
+  Dirty bool `yaml:"dirty,omitempty"`
+}
+
+/**
+* Snapshot of data of context
+*/
+type Snapshot struct {
+  Name string `yaml:"name"`
+  Context string `yaml:"context"`
+  CreatedAt time.Time `yaml:"createdAt"`
+  Services map[string]*Revision `yaml:"services"`
+  Volumes []*Volume `yaml:"volumes"`
+  /* folder of snapshot */
+  Dir string `yaml:"-"`
+}
+
+/**
+* Returns data volumes of system services of generated system compose file: named volumes (docker volumes
+* of compose project) and writable bind mounts of folders
+*/
+func Volumes(systemCompose *DockerComposeFileBuilder.DockerComposeFile, project string, contextDir string) (volumes []*Volume, err error) {
+  serviceNames := make([]string, 0, len(systemCompose.Services))
+  for serviceName := range systemCompose.Services {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  isAdded := make(map[string]bool)
+  archives := make(map[string]bool)
+  for _, serviceName := range serviceNames {
+    for _, mount := range systemCompose.Services[serviceName].Volumes {
+      parts := strings.Split(mount, ":")
+      if len(parts) < 2 || (len(parts) > 2 && strings.Contains(parts[2], "ro")) { continue }
+
+      volume := &Volume{Service: serviceName, Type: VOLUME_TYPE_NAMED, Name: parts[0]}
+      if namedVolume, isNamed := systemCompose.Volumes[parts[0]]; isNamed {
+        volume.Source = project + "_" + parts[0]
+        if namedVolume != nil && namedVolume.Name != "" {
+          volume.Source = namedVolume.Name
+        }
+      } else {
+        volume.Type = VOLUME_TYPE_BIND
+        if volume.Source, err = filepath.Abs(hostPath(parts[0], contextDir)); err != nil { return }
+        /* mounted files are configs of services */
+        if info, statErr := os.Stat(volume.Source); statErr != nil || !info.IsDir() { continue }
+        volume.Name = filepath.Base(volume.Source)
+      }
+      if isAdded[volume.Source] { continue }
+      isAdded[volume.Source] = true
+
+      volume.Archive = archiveName(serviceName + "-" + volume.Name, archives)
+      volumes = append(volumes, volume)
+    }
+  }
+
+  return
+}
+
+/**
+* Returns git revisions of application services of generated application compose file (services which are not
+* cloned are skipped)
+*/
+func Revisions(applicationCompose *DockerComposeFileBuilder.DockerComposeFile, contextDir string) (revisions map[string]*Revision, err error) {
+  revisions = make(map[string]*Revision)
+  for serviceName := range applicationCompose.Services {
+    gitState, err := services.GitStatus(contextDir + "/services", serviceName)
+    if err != nil {
+      return nil, fmt.Errorf("service '%s': %s", serviceName, err)
+    }
+    if gitState == nil { continue }
+
+    revisions[serviceName] = &Revision{Branch: gitState.Branch, Commit: gitState.Commit, Dirty: gitState.Dirty}
+  }
+
+  return
+}
+
+/**
+* Returns sorted names of services of volumes
+*/
+func ServiceNames(volumes []*Volume) (serviceNames []string) {
+  isAdded := make(map[string]bool)
+  for _, volume := range volumes {
+    if isAdded[volume.Service] { continue }
+    isAdded[volume.Service] = true
+    serviceNames = append(serviceNames, volume.Service)
+  }
+  sort.Strings(serviceNames)
+
+  return
+}
+
+/**
+* Archives volumes into new snapshot of context (name is set by time if it is empty), returns warnings
+* about volumes which don't exist yet (they are skipped)
+*/
+func Create(contextDir string, contextName string, name string, volumes []*Volume, revisions map[string]*Revision) (snapshot *Snapshot, warnings []string, err error) {
+  createdAt := time.Now()
+  if name == "" {
+    name = createdAt.Format(NAME_FORMAT)
+  }
+  if !namePattern.MatchString(name) {
+    return nil, nil, fmt.Errorf("snapshot name '%s' should contain letters, digits, '.', '_' and '-'", name)
+  }
+
+  snapshot = &Snapshot{Name: name, Context: contextName, CreatedAt: createdAt, Services: revisions, Dir: Dir(contextDir, name)}
+  if isExists, _ := files.IsExists(snapshot.Dir); isExists {
+    return nil, nil, fmt.Errorf("snapshot '%s' already exists", name)
+  }
+  if err = files.CreateDir(snapshot.Dir); err != nil { return }
+
+  absoluteDir, err := filepath.Abs(snapshot.Dir)
+  if err != nil { return }
+
+  for _, volume := range volumes {
+    if volume.Type == VOLUME_TYPE_NAMED {
+      if _, inspectErr := exec.Command("docker volume inspect " + util.Quote(volume.Source) + " >/dev/null 2>&1"); inspectErr != nil {
+        warnings = append(warnings, fmt.Sprintf("volume '%s' of service '%s' doesn't exist, it is skipped", volume.Name, volume.Service))
+        continue
+      }
+    }
+
+    /* archive is owned by current user, so snapshot could be deleted without root */
+    script := fmt.Sprintf("tar czf /snapshot/%s -C /data . && chown %d:%d /snapshot/%s", volume.Archive, os.Getuid(), os.Getgid(), volume.Archive)
+    if err = helper(volume.Source + ":/data:ro", absoluteDir + ":/snapshot", script); err != nil {
+      os.RemoveAll(snapshot.Dir)
+      return nil, nil, fmt.Errorf("volume '%s' of service '%s': %s", volume.Name, volume.Service, err)
+    }
+    snapshot.Volumes = append(snapshot.Volumes, volume)
+  }
+
+  data, err := yamlv3.Marshal(snapshot)
+  if err == nil {
+    err = files.WriteFileAtomic(filepath.Join(snapshot.Dir, METADATA_FILE), string(data))
+  }
+  if err != nil {
+    os.RemoveAll(snapshot.Dir)
+    return nil, nil, err
+  }
+
+  return
+}
+
+/**
+* Returns snapshots of context sorted by time of creation
+*/
+func List(contextDir string) (snapshots []*Snapshot, err error) {
+  entries, err := os.ReadDir(filepath.Join(contextDir, SNAPSHOTS_DIR))
+  if os.IsNotExist(err) { return nil, nil }
+  if err != nil { return }
+
+  for _, entry := range entries {
+    if !entry.IsDir() { continue }
+    if isExists, _ := files.IsExists(filepath.Join(contextDir, SNAPSHOTS_DIR, entry.Name(), METADATA_FILE)); !isExists { continue }
+
+    snapshot, err := Load(contextDir, entry.Name())
+    if err != nil { return nil, err }
+    snapshots = append(snapshots, snapshot)
+  }
+  sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+
+  return
+}
+
+/**
+* Loads snapshot of context
+*/
+func Load(contextDir string, name string) (snapshot *Snapshot, err error) {
+  dir := Dir(contextDir, name)
+  path := filepath.Join(dir, METADATA_FILE)
+  if isExists, _ := files.IsExists(path); !isExists || !namePattern.MatchString(name) {
+    return nil, fmt.Errorf("snapshot '%s' is not found, see 'devlab snapshot list'", name)
+  }
+
+  data, err := files.ReadTextFile(path)
+  if err != nil { return }
+
+  snapshot = &Snapshot{}
+  if err = yamlv3.Unmarshal([]byte(data), snapshot); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+  snapshot.Dir = dir
+
+  return
+}
+
+/**
+* Returns folder of snapshot of context
+*/
+func Dir(contextDir string, name string) string {
+  return filepath.Join(contextDir, SNAPSHOTS_DIR, name)
+}
+
+/**
+* Replaces data of volumes with archives of snapshot, missing docker volumes are created as volumes of compose project
+* (services using volumes should be stopped)
+*/
+func (snapshot *Snapshot) Restore(project string) error {
+  absoluteDir, err := filepath.Abs(snapshot.Dir)
+  if err != nil { return err }
+
+  for _, volume := range snapshot.Volumes {
+    switch volume.Type {
+    case VOLUME_TYPE_NAMED:
+      _, err = exec.Command(fmt.Sprintf("docker volume inspect %s >/dev/null 2>&1 || docker volume create --label com.docker.compose.project=%s --label com.docker.compose.volume=%s %s",
+        util.Quote(volume.Source), util.Quote(project), util.Quote(volume.Name), util.Quote(volume.Source)))
+    default:
+      err = files.CreateDir(volume.Source)
+    }
+    if err != nil {
+      return fmt.Errorf("volume '%s' of service '%s': %s", volume.Name, volume.Service, err)
+    }
+
+    script := fmt.Sprintf("find /data -mindepth 1 -delete && tar xzf /snapshot/%s -C /data", volume.Archive)
+    if err = helper(volume.Source + ":/data", absoluteDir + ":/snapshot:ro", script); err != nil {
+      return fmt.Errorf("volume '%s' of service '%s': %s", volume.Name, volume.Service, err)
+    }
+  }
+
+  return nil
+}
+
+/**
+* Returns differences between revisions of services of snapshot and current ones
+*/
+func (snapshot *Snapshot) Changes(revisions map[string]*Revision) (changes []string) {
+  serviceNames := make([]string, 0, len(snapshot.Services))
+  for serviceName := range snapshot.Services {
+    serviceNames = append(serviceNames, serviceName)
+  }
+  sort.Strings(serviceNames)
+
+  for _, serviceName := range serviceNames {
+    taken, current := snapshot.Services[serviceName], revisions[serviceName]
+    switch {
+    case current == nil:
+      changes = append(changes, fmt.Sprintf("%s: service is not cloned", serviceName))
+    case taken.Branch != current.Branch:
+      changes = append(changes, fmt.Sprintf("%s: branch %s -> %s", serviceName, taken.Branch, current.Branch))
+    case taken.Commit != current.Commit:
+      changes = append(changes, fmt.Sprintf("%s: commit %s -> %s", serviceName, short(taken.Commit), short(current.Commit)))
+    }
+  }
+
+  return
+}
+
+/**
+* Returns size of archives of snapshot in bytes
+*/
+func (snapshot *Snapshot) Size() (size int64) {
+  for _, volume := range snapshot.Volumes {
+    if info, err := os.Stat(filepath.Join(snapshot.Dir, volume.Archive)); err == nil {
+      size += info.Size()
+    }
+  }
+
+  return
+}
+
+/**
+* Deletes snapshot
+*/
+func (snapshot *Snapshot) Delete() error {
+  return os.RemoveAll(snapshot.Dir)
+}
+
+/**
+* Runs script in helper container with volume mounted as /data and snapshot folder mounted as /snapshot
+*/
+func helper(dataMount string, snapshotMount string, script string) error {
+  out, err := exec.Command(fmt.Sprintf("docker run --rm -v %s -v %s %s sh -c %s 2>&1", util.Quote(dataMount), util.Quote(snapshotMount), HELPER_IMAGE, util.Quote(script)))
+  if err != nil {
+    return fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
+  }
+
+  return nil
+}
+
+/**
+* Returns absolute or context relative path of bind mount (paths of generated compose files are relative to context folder)
+*/
+func hostPath(source string, contextDir string) string {
+  if strings.HasPrefix(source, "~/") {
+    home, _ := os.UserHomeDir()
+    return filepath.Join(home, source[2:])
+  }
+  if filepath.IsAbs(source) {
+    return source
+  }
+
+  return filepath.Join(contextDir, source)
+}
+
+/**
+* Returns unique name of archive
+*/
+func archiveName(name string, archives map[string]bool) string {
+  name = regexp.MustCompile(`[^a-zA-Z0-9._-]+`).ReplaceAllString(name, "_")
+  archive := name + ".tar.gz"
+  for i := 2; archives[archive]; i++ {
+    archive = fmt.Sprintf("%s-%d.tar.gz", name, i)
+  }
+  archives[archive] = true
+
+  return archive
+}
+
+func short(commit string) string {
+  if len(commit) > 8 { return commit[:8] }
+  return commit
+}
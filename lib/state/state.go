@@ -0,0 +1,48 @@
+package state
+
+import (
+  "fmt"
+  "devlab/lib/files"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+/* File with state of context which devlab keeps between runs (it is not edited by user) */
+const STATE_FILE = "state.yml"
+
+/**
+* State of context
+*/
+type State struct {
+  /* host ports allocated for published ports of services: service => container port => host port */
+  Ports map[string]map[string]int `yaml:"ports,omitempty"`
+}
+
+/**
+* Loads state of context (empty state if there is no state file yet)
+*/
+func Load(contextDir string) (contextState *State, err error) {
+  contextState = &State{Ports: make(map[string]map[string]int)}
+
+  path := contextDir + "/" + STATE_FILE
+  isExists, _ := files.IsExists(path)
+  if !isExists { return }
+
+  data, err := files.ReadTextFile(path)
+  if err != nil { return }
+
+  if err = yamlv3.Unmarshal([]byte(data), contextState); err != nil {
+    return contextState, fmt.Errorf("%s: %s", path, err)
+  }
+  if contextState.Ports == nil {
+    contextState.Ports = make(map[string]map[string]int)
+  }
+
+  return
+}
+
+/**
+* Saves state of context
+*/
+func (contextState *State) Save(contextDir string) error {
+  return files.WriteYaml(contextDir + "/" + STATE_FILE, contextState)
+}
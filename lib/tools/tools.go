@@ -0,0 +1,164 @@
+package tools
+
+import (
+  "fmt"
+  "html"
+  "net/http"
+  "strings"
+  "time"
+  "devlab/lib/env"
+  "devlab/lib/util"
+)
+
+/* How long to wait for web ui of tool */
+const UI_TIMEOUT = 30 * time.Second
+
+/**
+* Utility run as throwaway container attached to network of context, ${VAR} in its arguments, environment and login
+* are variables of context (e.g. ${KAFKA_HOST}, ${POSTGRES_PASSWORD}, see 'devlab env show <context>')
+*/
+type Tool struct {
+  Description string
+  Image string
+  /* system services tool connects to */
+  Services []string
+  /* arguments passed before arguments of user */
+  Args []string
+  Env map[string]string
+  /* container port of web ui: tool is run in background and opened in browser */
+  Port int
+  /* fields of login form posted to web ui, so user is logged in */
+  Login map[string]string
+}
+
+var Tools = map[string]*Tool{
+  "kafkacat": {
+    Description: "kafka consumer and producer (kcat), e.g. 'devlab tool kafkacat -L'",
+    Image: "edenhill/kcat:1.7.1",
+    Services: []string{"kafka"},
+    Args: []string{"-b", "${KAFKA_HOST}:${KAFKA_PORT:-9092}"},
+  },
+  "adminer": {
+    Description: "web ui of databases logged in postgres of context",
+    Image: "adminer",
+    Services: []string{"postgres"},
+    Env: map[string]string{"ADMINER_DEFAULT_SERVER": "${POSTGRES_HOST}"},
+    Port: 8080,
+    Login: map[string]string{
+      "auth[driver]": "pgsql",
+      "auth[server]": "${POSTGRES_HOST}:${POSTGRES_PORT:-5432}",
+      "auth[username]": "postgres",
+      "auth[password]": "${POSTGRES_PASSWORD}",
+      "auth[db]": "postgres",
+    },
+  },
+  "nats": {
+    Description: "NATS client (nats cli), e.g. 'devlab tool nats sub \">\"'",
+    Image: "natsio/nats-box",
+    Services: []string{"nats"},
+    Args: []string{"nats"},
+    Env: map[string]string{"NATS_URL": "nats://${NATS_HOST}:${NATS_PORT:-4222}"},
+  },
+  "redis-dashboard": {
+    Description: "web ui of redis (redis commander)",
+    Image: "rediscommander/redis-commander",
+    Services: []string{"redis"},
+    Env: map[string]string{"REDIS_HOSTS": "${CONTEXT_NAME}:${REDIS_HOST}:${REDIS_PORT:-6379}"},
+    Port: 8081,
+  },
+}
+
+/**
+* Returns sorted names of tools
+*/
+func Names() []string {
+  return util.SortedKeys(Tools)
+}
+
+/**
+* Returns tool by name
+*/
+func Find(name string) (*Tool, error) {
+  tool, ok := Tools[name]
+  if !ok {
+    return nil, fmt.Errorf("unknown tool '%s', tools: %s", name, strings.Join(Names(), ", "))
+  }
+
+  return tool, nil
+}
+
+/**
+* Returns system services of tool which are not services of context
+*/
+func (tool *Tool) Missing(variables map[string]string) (missing []string) {
+  for _, serviceName := range tool.Services {
+    if _, ok := variables[env.Name(serviceName) + "_HOST"]; !ok {
+      missing = append(missing, serviceName)
+    }
+  }
+
+  return
+}
+
+/**
+* Returns 'docker run' arguments of tool container (image and arguments of tool are the last ones)
+*/
+func (tool *Tool) RunArgs(variables map[string]string, args []string) (runArgs []string, err error) {
+  runArgs = []string{"--rm", "--network", variables["DOCKER_NETWORK"]}
+
+  for _, name := range util.SortedKeys(tool.Env) {
+    value, err := env.Expand(tool.Env[name], variables)
+    if err != nil { return nil, err }
+    runArgs = append(runArgs, "-e", name + "=" + value)
+  }
+
+  runArgs = append(runArgs, tool.Image)
+  for _, arg := range tool.Args {
+    value, err := env.Expand(arg, variables)
+    if err != nil { return nil, err }
+    runArgs = append(runArgs, value)
+  }
+
+  return append(runArgs, args...), nil
+}
+
+/**
+* Returns html page which posts login form to web ui of tool ("" if tool has no login)
+*/
+func (tool *Tool) LoginPage(url string, variables map[string]string) (string, error) {
+  if len(tool.Login) == 0 { return "", nil }
+
+  var fields []string
+  for _, name := range util.SortedKeys(tool.Login) {
+    value, err := env.Expand(tool.Login[name], variables)
+    if err != nil { return "", err }
+    fields = append(fields, fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, html.EscapeString(name), html.EscapeString(value)))
+  }
+
+  return fmt.Sprintf(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form method="post" action="%s">
+%s
+</form>
+</body></html>
+`, html.EscapeString(url), strings.Join(fields, "\n")), nil
+}
+
+/**
+* Waits until web ui responds
+*/
+func WaitUi(url string, timeout time.Duration) error {
+  client := http.Client{Timeout: 2 * time.Second}
+  deadline := time.Now().Add(timeout)
+  for {
+    response, err := client.Get(url)
+    if err == nil {
+      response.Body.Close()
+      return nil
+    }
+    if time.Now().After(deadline) {
+      return fmt.Errorf("%s doesn't respond after %s: %s", url, timeout, err)
+    }
+    time.Sleep(time.Second)
+  }
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+  "bufio"
+  "fmt"
+  "os"
+  "sort"
+  "strings"
+  "devlab/lib/logger"
+)
+
+/**
+* Quotes value for shell command
+*/
+func Quote(value string) string {
+  return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+/**
+* Quotes values for shell command and joins them with spaces
+*/
+func QuoteAll(values []string) string {
+  quoted := make([]string, len(values))
+  for i, value := range values {
+    quoted[i] = Quote(value)
+  }
+
+  return strings.Join(quoted, " ")
+}
+
+/**
+* Returns name of the only context of contexts folder (error with usage of command if there are no contexts
+* or there are several ones)
+*/
+func OnlyContext(contextsPath string, usage string) (string, error) {
+  entries, err := os.ReadDir(contextsPath)
+  if err != nil { return "", err }
+
+  var contextNames []string
+  for _, entry := range entries {
+    if entry.IsDir() {
+      contextNames = append(contextNames, entry.Name())
+    }
+  }
+
+  if len(contextNames) != 1 {
+    return "", fmt.Errorf("context should be set: %s (contexts: %s)", usage, strings.Join(contextNames, ", "))
+  }
+
+  return contextNames[0], nil
+}
+
+/**
+* Asks question and returns true if answer is 'y'
+*/
+func Confirm(question string) bool {
+  logger.Info("%s", question)
+  input := bufio.NewScanner(os.Stdin)
+
+  return input.Scan() && strings.ToLower(strings.TrimSpace(input.Text())) == "y"
+}
+
+/**
+* Returns keys of map in sorted order
+*/
+func SortedKeys[V any](values map[string]V) []string {
+  keys := make([]string, 0, len(values))
+  for key := range values {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+
+  return keys
+}
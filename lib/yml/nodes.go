@@ -0,0 +1,296 @@
+package yml
+
+import (
+  "bytes"
+  "fmt"
+  "reflect"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+/**
+* Key and value of mapping which keeps order of keys as they are added
+*/
+type Pair struct {
+  Key string
+  Value interface{}
+}
+
+type Tree []Pair
+
+/* Plain scalars which yaml parsers resolve to not string values (bool, null, numbers) */
+var notStringScalar = regexp.MustCompile(`^(?i:y|n|yes|no|on|off|true|false|null|~|[-+]?(\.inf|\.nan)|[-+]?[0-9][0-9_]*(\.[0-9_]*)?([eE][-+]?[0-9]+)?|0x[0-9a-f]+|0o?[0-7]+)$`)
+
+/* Yaml 1.1 sexagesimal numbers ('22:22' is parsed as 1342 by old parsers, e.g. in ports) */
+var sexagesimal = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?$`)
+
+/**
+* Serializes value to yaml: maps are written with sorted keys, structs in order of fields (with `yaml` tags),
+* Tree in order of pairs, yaml.Marshaler as it marshals itself, strings are quoted if they would be parsed
+* as something else
+*/
+func Marshal(value interface{}) (data []byte, err error) {
+  node, err := ToNode(value)
+  if err != nil { return }
+
+  return Render(node)
+}
+
+/**
+* Renders yaml node (document or any other node) with 2 spaces indent
+*/
+func Render(node *yamlv3.Node) ([]byte, error) {
+  var buffer bytes.Buffer
+  encoder := yamlv3.NewEncoder(&buffer)
+  encoder.SetIndent(2)
+
+  if err := encoder.Encode(node); err != nil {
+    return nil, err
+  }
+  if err := encoder.Close(); err != nil {
+    return nil, err
+  }
+
+  return buffer.Bytes(), nil
+}
+
+/**
+* Converts value to yaml node tree
+*/
+func ToNode(value interface{}) (*yamlv3.Node, error) {
+  switch typed := value.(type) {
+  case *yamlv3.Node:
+    return typed, nil
+  case Tree:
+    node := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+    for _, pair := range typed {
+      if err := appendPair(node, pair.Key, pair.Value); err != nil { return nil, err }
+    }
+    return node, nil
+  }
+
+  return valueToNode(reflect.ValueOf(value))
+}
+
+/**
+* Parses yaml document keeping comments
+*/
+func ParseDocument(data string) (*yamlv3.Node, error) {
+  document := &yamlv3.Node{}
+  if err := yamlv3.Unmarshal([]byte(data), document); err != nil {
+    return nil, err
+  }
+
+  if document.Kind == 0 {
+    document.Kind = yamlv3.DocumentNode
+    document.Content = []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}
+  }
+
+  return document, nil
+}
+
+/**
+* Sets value at path of keys in yaml document, missing mappings are created.
+* Comments of existing keys are kept
+*/
+func SetValue(document *yamlv3.Node, path []string, value interface{}) error {
+  if len(path) == 0 {
+    return fmt.Errorf("empty yaml path")
+  }
+
+  node := document
+  if node.Kind == yamlv3.DocumentNode {
+    node = node.Content[0]
+  }
+
+  valueNode, err := ToNode(value)
+  if err != nil { return err }
+
+  for i, key := range path {
+    if node.Kind != yamlv3.MappingNode {
+      if node.Tag != "!!null" {
+        return fmt.Errorf("'%s' is not a mapping", strings.Join(path[:i], "."))
+      }
+      node.Kind, node.Tag, node.Value = yamlv3.MappingNode, "!!map", ""
+    }
+
+    child := MappingValue(node, key)
+    if i == len(path) - 1 {
+      if child == nil {
+        node.Content = append(node.Content, scalarNode(key), valueNode)
+      } else {
+        valueNode.LineComment, valueNode.HeadComment, valueNode.FootComment = child.LineComment, child.HeadComment, child.FootComment
+        *child = *valueNode
+      }
+      return nil
+    }
+
+    if child == nil {
+      child = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+      node.Content = append(node.Content, scalarNode(key), child)
+    }
+    node = child
+  }
+
+  return nil
+}
+
+/**
+* Returns value node of mapping by key (nil if there is no such key)
+*/
+func MappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+  for i := 0; i + 1 < len(mapping.Content); i += 2 {
+    if mapping.Content[i].Value == key {
+      return mapping.Content[i + 1]
+    }
+  }
+
+  return nil
+}
+
+func valueToNode(value reflect.Value) (*yamlv3.Node, error) {
+  if !value.IsValid() {
+    return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null", Value: "null"}, nil
+  }
+
+  if value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+    if value.IsNil() {
+      return valueToNode(reflect.Value{})
+    }
+    if node, ok := value.Interface().(*yamlv3.Node); ok {
+      return node, nil
+    }
+  }
+
+  /* types with own yaml representation (e.g. lists which could be written as maps) */
+  if marshaler, ok := value.Interface().(yamlv3.Marshaler); ok {
+    marshaled, err := marshaler.MarshalYAML()
+    if err != nil { return nil, err }
+    return ToNode(marshaled)
+  }
+
+  if value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+    return valueToNode(value.Elem())
+  }
+
+  if tree, ok := value.Interface().(Tree); ok {
+    return ToNode(tree)
+  }
+
+  switch value.Kind() {
+  case reflect.String:
+    return scalarNode(value.String()), nil
+
+  case reflect.Bool:
+    return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value.Bool())}, nil
+
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(value.Int(), 10)}, nil
+
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!int", Value: strconv.FormatUint(value.Uint(), 10)}, nil
+
+  case reflect.Float32, reflect.Float64:
+    return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(value.Float(), 'g', -1, 64)}, nil
+
+  case reflect.Slice, reflect.Array:
+    node := &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+    for i := 0; i < value.Len(); i++ {
+      item, err := valueToNode(value.Index(i))
+      if err != nil { return nil, err }
+      node.Content = append(node.Content, item)
+    }
+    return node, nil
+
+  case reflect.Map:
+    keys := make([]string, 0, value.Len())
+    values := make(map[string]reflect.Value)
+    for _, key := range value.MapKeys() {
+      keyString := fmt.Sprint(key.Interface())
+      keys = append(keys, keyString)
+      values[keyString] = value.MapIndex(key)
+    }
+    sort.Strings(keys)
+
+    node := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+    for _, key := range keys {
+      if err := appendPair(node, key, values[key].Interface()); err != nil { return nil, err }
+    }
+    return node, nil
+
+  case reflect.Struct:
+    node := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+    valueType := value.Type()
+    for i := 0; i < valueType.NumField(); i++ {
+      field := valueType.Field(i)
+      if field.PkgPath != "" { continue }
+
+      name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+      if name == "-" { continue }
+      if name == "" {
+        name = strings.ToLower(field.Name)
+      }
+      if strings.Contains(options, "omitempty") && isEmpty(value.Field(i)) { continue }
+
+      if strings.Contains(options, "inline") {
+        inlineNode, err := valueToNode(value.Field(i))
+        if err != nil { return nil, err }
+        if inlineNode.Kind == yamlv3.MappingNode {
+          node.Content = append(node.Content, inlineNode.Content...)
+        }
+        continue
+      }
+
+      if err := appendPair(node, name, value.Field(i).Interface()); err != nil { return nil, err }
+    }
+    return node, nil
+  }
+
+  return nil, fmt.Errorf("value of type %s couldn't be written to yaml", value.Type())
+}
+
+func isEmpty(value reflect.Value) bool {
+  switch value.Kind() {
+  case reflect.Slice, reflect.Map:
+    return value.Len() == 0
+  }
+
+  return value.IsZero()
+}
+
+func appendPair(mapping *yamlv3.Node, key string, value interface{}) error {
+  valueNode, err := ToNode(value)
+  if err != nil { return err }
+
+  mapping.Content = append(mapping.Content, scalarNode(key), valueNode)
+  return nil
+}
+
+/**
+* Returns string scalar node, quoted if the string is special for yaml ('${VAR}', ': ', '#', 'true', '2.0', ...)
+*/
+func scalarNode(value string) *yamlv3.Node {
+  node := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+  if needsQuoting(value) {
+    node.Style = yamlv3.DoubleQuotedStyle
+  }
+
+  return node
+}
+
+func needsQuoting(value string) bool {
+  if value == "" || notStringScalar.MatchString(value) || sexagesimal.MatchString(value) {
+    return true
+  }
+  if strings.TrimSpace(value) != value || strings.ContainsAny(value, "\n\t") {
+    return true
+  }
+  if strings.Contains(value, "${") || strings.Contains(value, ": ") || strings.HasSuffix(value, ":") || strings.Contains(value, " #") {
+    return true
+  }
+
+  return strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`")
+}
@@ -0,0 +1,221 @@
+package yml
+
+import (
+  "testing"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+func TestMarshalQuoting(t *testing.T) {
+  tests := []struct {
+    value string
+    rendered string
+  }{
+    {value: "postgres", rendered: "postgres"},
+    {value: "http://host/?a=b", rendered: "http://host/?a=b"},
+    {value: "a#b", rendered: "a#b"},
+    {value: "", rendered: `""`},
+    {value: "2.0", rendered: `"2.0"`},
+    {value: "5432", rendered: `"5432"`},
+    {value: "0x1F", rendered: `"0x1F"`},
+    {value: "1e3", rendered: `"1e3"`},
+    {value: "yes", rendered: `"yes"`},
+    {value: "No", rendered: `"No"`},
+    {value: "on", rendered: `"on"`},
+    {value: "off", rendered: `"off"`},
+    {value: "true", rendered: `"true"`},
+    {value: "null", rendered: `"null"`},
+    {value: "~", rendered: `"~"`},
+    {value: "22:22", rendered: `"22:22"`},
+    {value: "key: value", rendered: `"key: value"`},
+    {value: "key:", rendered: `"key:"`},
+    {value: "value # comment", rendered: `"value # comment"`},
+    {value: "#comment", rendered: `"#comment"`},
+    {value: "${POSTGRES_HOST}", rendered: `"${POSTGRES_HOST}"`},
+    {value: "- item", rendered: `"- item"`},
+    {value: "*alias", rendered: `"*alias"`},
+    {value: " padded", rendered: `" padded"`},
+    {value: "two\nlines", rendered: `"two\nlines"`},
+  }
+
+  for _, test := range tests {
+    t.Run(test.value, func(t *testing.T) {
+      data, err := Marshal(map[string]string{"key": test.value})
+      if err != nil { t.Fatal(err) }
+
+      if expected := "key: " + test.rendered + "\n"; string(data) != expected {
+        t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+      }
+
+      /* value is parsed back as the same string */
+      var parsed map[string]interface{}
+      if err = yamlv3.Unmarshal(data, &parsed); err != nil { t.Fatal(err) }
+      if parsed["key"] != test.value {
+        t.Errorf("expected to parse '%s', got %#v", test.value, parsed["key"])
+      }
+    })
+  }
+}
+
+type marshalService struct {
+  Image string `yaml:"image"`
+  Command string `yaml:"command,omitempty"`
+  Ports []string `yaml:"ports,omitempty"`
+  Restart string
+  Options marshalOptions `yaml:",inline"`
+  Ignored string `yaml:"-"`
+  internal string
+}
+
+type marshalOptions struct {
+  Privileged bool `yaml:"privileged,omitempty"`
+  Replicas int `yaml:"replicas"`
+}
+
+func TestMarshalKeyOrder(t *testing.T) {
+  tests := []struct {
+    name string
+    value interface{}
+    rendered string
+  }{
+    {
+      name: "map keys are sorted",
+      value: map[string]interface{}{"volumes": nil, "services": map[string]int{"web": 2, "api": 1}, "networks": []string{"b", "a"}},
+      rendered: "networks:\n  - b\n  - a\nservices:\n  api: 1\n  web: 2\nvolumes: null\n",
+    },
+    {
+      name: "struct fields in order of declaration",
+      value: marshalService{Image: "api", Ports: []string{"8080"}, Restart: "always", Options: marshalOptions{Replicas: 2}, Ignored: "x", internal: "y"},
+      rendered: "image: api\nports:\n  - \"8080\"\nrestart: always\nreplicas: 2\n",
+    },
+    {
+      name: "tree in order of pairs",
+      value: Tree{{Key: "contexts-path", Value: "contexts"}, {Key: "base-branch", Value: "develop"}, {Key: "git", Value: Tree{{Key: "port", Value: 22}, {Key: "host", Value: "github.com"}}}},
+      rendered: "contexts-path: contexts\nbase-branch: develop\ngit:\n  port: 22\n  host: github.com\n",
+    },
+    {
+      name: "pointers",
+      value: map[string]*string{"HOME": nil, "FOO": stringPointer("bar")},
+      rendered: "FOO: bar\nHOME: null\n",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      data, err := Marshal(test.value)
+      if err != nil { t.Fatal(err) }
+
+      if string(data) != test.rendered {
+        t.Errorf("expected:\n%s\ngot:\n%s", test.rendered, data)
+      }
+    })
+  }
+
+  if _, err := Marshal(map[string]interface{}{"channel": make(chan int)}); err == nil {
+    t.Errorf("value which couldn't be written to yaml should be an error")
+  }
+}
+
+func TestSetValue(t *testing.T) {
+  const SETTINGS = `# settings of context
+context:
+  task:
+    name: foo       # name of task
+    base-branch: develop
+system-services:
+  # message broker
+  kafka:
+    enabled: true
+`
+
+  tests := []struct {
+    name string
+    data string
+    path []string
+    value interface{}
+    rendered string
+    isError bool
+  }{
+    {
+      name: "existing key keeps its comment",
+      data: SETTINGS,
+      path: []string{"context", "task", "name"},
+      value: "bar",
+      rendered: "# settings of context\ncontext:\n  task:\n    name: bar # name of task\n    base-branch: develop\nsystem-services:\n  # message broker\n  kafka:\n    enabled: true\n",
+    },
+    {
+      name: "new key is appended",
+      data: SETTINGS,
+      path: []string{"system-services", "kafka", "extensions"},
+      value: "ui",
+      rendered: "# settings of context\ncontext:\n  task:\n    name: foo # name of task\n    base-branch: develop\nsystem-services:\n  # message broker\n  kafka:\n    enabled: true\n    extensions: ui\n",
+    },
+    {
+      name: "missing mappings are created",
+      data: SETTINGS,
+      path: []string{"system-services", "postgres", "enabled"},
+      value: true,
+      rendered: "# settings of context\ncontext:\n  task:\n    name: foo # name of task\n    base-branch: develop\nsystem-services:\n  # message broker\n  kafka:\n    enabled: true\n  postgres:\n    enabled: true\n",
+    },
+    {
+      name: "null value becomes mapping",
+      data: "system-services:\n  kafka:\n",
+      path: []string{"system-services", "kafka", "enabled"},
+      value: false,
+      rendered: "system-services:\n  kafka:\n    enabled: false\n",
+    },
+    {
+      name: "string value is quoted",
+      data: "version: 3.4\n",
+      path: []string{"version"},
+      value: "2.0",
+      rendered: "version: \"2.0\"\n",
+    },
+    {
+      name: "empty document",
+      data: "",
+      path: []string{"base-branch"},
+      value: "main",
+      rendered: "base-branch: main\n",
+    },
+    {
+      name: "value is not a mapping",
+      data: SETTINGS,
+      path: []string{"context", "task", "name", "first"},
+      value: "bar",
+      isError: true,
+    },
+    {
+      name: "empty path",
+      data: SETTINGS,
+      isError: true,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      document, err := ParseDocument(test.data)
+      if err != nil { t.Fatal(err) }
+
+      err = SetValue(document, test.path, test.value)
+      if test.isError {
+        if err == nil {
+          t.Errorf("expected error")
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      data, err := Render(document)
+      if err != nil { t.Fatal(err) }
+      if string(data) != test.rendered {
+        t.Errorf("expected:\n%s\ngot:\n%s", test.rendered, data)
+      }
+    })
+  }
+}
+
+func stringPointer(value string) *string {
+  return &value
+}
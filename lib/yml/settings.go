@@ -0,0 +1,111 @@
+package yml
+
+import (
+  "fmt"
+  yamlv3 "gopkg.in/yaml.v3"
+)
+
+/* Param of group of settings set by scalar ('observability: elk' is read as 'context: observability: value: elk') */
+const SETTINGS_VALUE_PARAM = "value"
+
+/* Section of groups set by scalar at top level of settings */
+const SETTINGS_SCALAR_SECTION = "context"
+
+/**
+* Parses settings of context as sections of groups of params ('system-services: kafka: enabled: true').
+* Besides these three levels it reads:
+* - group set by scalar as group with single 'value' param: 'context: observability: elk' and
+*   top level 'observability: elk' are both read as 'context: observability: value: elk'
+* - nested sections as '<section>-<subsection>': 'kafka: topics: orders: partitions: 3' is read
+*   as 'kafka-topics: orders: partitions: 3'
+*/
+func ParseSettingsYAML(data string) (settings map[string]map[string]map[string]string, err error) {
+  settings = make(map[string]map[string]map[string]string)
+
+  document := &yamlv3.Node{}
+  if err = yamlv3.Unmarshal([]byte(data), document); err != nil { return }
+  if document.Kind == 0 { return }
+
+  root := document.Content[0]
+  if root.Kind != yamlv3.MappingNode {
+    return settings, fmt.Errorf("settings should be a mapping of sections")
+  }
+
+  for i := 0; i + 1 < len(root.Content); i += 2 {
+    sectionName, section := root.Content[i].Value, root.Content[i + 1]
+
+    if isValue(section) {
+      addGroup(settings, SETTINGS_SCALAR_SECTION, sectionName, map[string]string{SETTINGS_VALUE_PARAM: section.Value})
+      continue
+    }
+    if err = addSection(settings, sectionName, section); err != nil { return }
+  }
+
+  return
+}
+
+func addSection(settings map[string]map[string]map[string]string, sectionName string, section *yamlv3.Node) error {
+  if settings[sectionName] == nil {
+    settings[sectionName] = make(map[string]map[string]string)
+  }
+  if section.Tag == "!!null" { return nil }
+  if section.Kind != yamlv3.MappingNode {
+    return fmt.Errorf("section '%s' should be a mapping", sectionName)
+  }
+
+  for i := 0; i + 1 < len(section.Content); i += 2 {
+    groupName, group := section.Content[i].Value, section.Content[i + 1]
+
+    if isValue(group) {
+      addGroup(settings, sectionName, groupName, map[string]string{SETTINGS_VALUE_PARAM: group.Value})
+      continue
+    }
+    if isNestedSection(group) {
+      if err := addSection(settings, sectionName + "-" + groupName, group); err != nil { return err }
+      continue
+    }
+
+    params := make(map[string]string)
+    for j := 0; group.Kind == yamlv3.MappingNode && j + 1 < len(group.Content); j += 2 {
+      param, value := group.Content[j].Value, group.Content[j + 1]
+      if value.Kind != yamlv3.ScalarNode {
+        return fmt.Errorf("param '%s.%s.%s' should be a value", sectionName, groupName, param)
+      }
+      params[param] = scalarValue(value)
+    }
+    addGroup(settings, sectionName, groupName, params)
+  }
+
+  return nil
+}
+
+func addGroup(settings map[string]map[string]map[string]string, sectionName string, groupName string, params map[string]string) {
+  if settings[sectionName] == nil {
+    settings[sectionName] = make(map[string]map[string]string)
+  }
+  settings[sectionName][groupName] = params
+}
+
+/**
+* Not null scalar (null group is a group without params)
+*/
+func isValue(node *yamlv3.Node) bool {
+  return node.Kind == yamlv3.ScalarNode && node.Tag != "!!null"
+}
+
+/**
+* Mapping with mappings as values (params of groups are scalars)
+*/
+func isNestedSection(node *yamlv3.Node) bool {
+  if node.Kind != yamlv3.MappingNode { return false }
+
+  for i := 1; i < len(node.Content); i += 2 {
+    if node.Content[i].Kind == yamlv3.MappingNode { return true }
+  }
+  return false
+}
+
+func scalarValue(node *yamlv3.Node) string {
+  if node.Tag == "!!null" { return "" }
+  return node.Value
+}
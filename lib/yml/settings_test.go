@@ -0,0 +1,96 @@
+package yml
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestParseSettingsYAML(t *testing.T) {
+  tests := []struct {
+    name string
+    data string
+    expected map[string]map[string]map[string]string
+    isError bool
+  }{
+    {
+      name: "three levels",
+      data: "context:\n  task:\n    name: foo\n    base-branch:\n  build:\nsystem-services:\n  kafka:\n    enabled: true\n    partitions: 3\npostgres-roles:\n",
+      expected: map[string]map[string]map[string]string{
+        "context": {"task": {"name": "foo", "base-branch": ""}, "build": {}},
+        "system-services": {"kafka": {"enabled": "true", "partitions": "3"}},
+        "postgres-roles": {},
+      },
+    },
+    {
+      name: "group set by scalar in context section",
+      data: "context:\n  observability: elk\n  task:\n    name: foo\n",
+      expected: map[string]map[string]map[string]string{
+        "context": {"observability": {SETTINGS_VALUE_PARAM: "elk"}, "task": {"name": "foo"}},
+      },
+    },
+    {
+      name: "group set by scalar at top level",
+      data: "observability: elk\ncontext:\n  task:\n    name: foo\n",
+      expected: map[string]map[string]map[string]string{
+        "context": {"observability": {SETTINGS_VALUE_PARAM: "elk"}, "task": {"name": "foo"}},
+      },
+    },
+    {
+      name: "nested section",
+      data: "kafka:\n  topics:\n    orders:\n      partitions: 3\n      configs: retention.ms=1000\n    payments: {}\n",
+      expected: map[string]map[string]map[string]string{
+        "kafka": {},
+        "kafka-topics": {"orders": {"partitions": "3", "configs": "retention.ms=1000"}, "payments": {}},
+      },
+    },
+    {
+      name: "nested section without groups",
+      data: "kafka:\n  topics:\n",
+      expected: map[string]map[string]map[string]string{"kafka": {"topics": {}}},
+    },
+    {
+      name: "flat form of nested section",
+      data: "kafka-topics:\n  orders:\n    partitions: 3\n",
+      expected: map[string]map[string]map[string]string{"kafka-topics": {"orders": {"partitions": "3"}}},
+    },
+    {
+      name: "empty settings",
+      data: "",
+      expected: map[string]map[string]map[string]string{},
+    },
+    {
+      name: "list as param",
+      data: "system-services:\n  kafka:\n    extensions:\n      - ui\n",
+      isError: true,
+    },
+    {
+      name: "list as section",
+      data: "system-services:\n  - kafka\n",
+      isError: true,
+    },
+    {
+      name: "invalid yaml",
+      data: "context: [\n",
+      isError: true,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      settings, err := ParseSettingsYAML(test.data)
+      if test.isError {
+        if err == nil {
+          t.Errorf("expected error, got %v", settings)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+      }
+
+      if !reflect.DeepEqual(settings, test.expected) {
+        t.Errorf("expected:\n%v\ngot:\n%v", test.expected, settings)
+      }
+    })
+  }
+}
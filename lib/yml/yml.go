@@ -1,6 +1,7 @@
 package yml
 
 import (
+  "strings"
   "github.com/gopkg.in/yaml"
   "devlab/lib/errors"
 )
@@ -26,3 +27,17 @@ func ParseThreeLevelYAML(data string) (parsedData map[string]map[string]map[stri
   return
 }
 
+/**
+* Returns keys of one level yaml in the order they are declared
+*/
+func OneLevelYAMLKeys(data string) (keys []string) {
+  for _, line := range strings.Split(data, "\n") {
+    if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "#") { continue }
+
+    if colon := strings.Index(line, ":"); colon > 0 {
+      keys = append(keys, strings.TrimSpace(line[:colon]))
+    }
+  }
+
+  return
+}